@@ -0,0 +1,207 @@
+// Package api implements the attachment handlers for the template plugin
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"go.lumeweb.com/httputil"
+	"go.lumeweb.com/portal-plugin-template/internal"
+	"go.lumeweb.com/portal-plugin-template/internal/api/messages"
+	"go.lumeweb.com/portal-plugin-template/internal/db/models"
+	"go.lumeweb.com/portal-plugin-template/internal/protocol"
+	"go.lumeweb.com/portal/core"
+	"go.uber.org/zap"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+// attachmentStatusUnknown is reported when an attachment's upload workflow cannot be read
+const attachmentStatusUnknown = "unknown"
+
+// attachmentNameFromRequest reads the file name from the name query parameter,
+// falling back to the filename of a Content-Disposition header
+func attachmentNameFromRequest(r *http.Request) string {
+	if name := r.URL.Query().Get("name"); name != "" {
+		return name
+	}
+
+	if _, params, err := mime.ParseMediaType(r.Header.Get("Content-Disposition")); err == nil {
+		return params["filename"]
+	}
+
+	return ""
+}
+
+// attachmentUpload stages the file of an attachment request through the upload workflow
+type attachmentUpload struct {
+	w      http.ResponseWriter
+	r      *http.Request
+	api    *API
+	proto  *protocol.Protocol
+	userID uint
+	name   string
+	staged *protocol.StagedUpload
+}
+
+// Stage reads the file from the request body into temporary storage
+func (u *attachmentUpload) Stage() (*models.ItemAttachment, error) {
+	body, size, err := u.api.uploadBodyFromRequest(u.w, u.r)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	u.staged, err = u.proto.StageUpload(u.r.Context(), u.userID, body, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ItemAttachment{
+		Hash: u.proto.EncodeFileName(u.staged.Hash()),
+		Name: u.name,
+		Size: u.staged.Size(),
+	}, nil
+}
+
+// Store starts the upload workflow storing the staged file
+func (u *attachmentUpload) Store() (uint, error) {
+	state, err := u.proto.StartUpload(u.r.Context(), u.staged)
+	if err != nil {
+		return 0, err
+	}
+
+	uploadID, err := strconv.ParseUint(state.ID, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid upload ID %q: %w", state.ID, err)
+	}
+
+	return uint(uploadID), nil
+}
+
+// Discard releases the staged file
+func (u *attachmentUpload) Discard() {
+	if err := u.proto.DiscardUpload(u.r.Context(), u.staged); err != nil {
+		u.api.logger.Error("failed to discard attachment upload", zap.Error(err))
+	}
+}
+
+// attachmentWithStatus combines an attachment with the status of its upload workflow
+func (a *API) attachmentWithStatus(proto *protocol.Protocol, attachment models.ItemAttachment) messages.Attachment {
+	result := messages.Attachment{ItemAttachment: attachment, Status: attachmentStatusUnknown}
+
+	state, err := proto.GetUploadStatus(strconv.FormatUint(uint64(attachment.UploadID), 10))
+	if err != nil {
+		// A missing workflow shouldn't hide the attachment itself
+		a.logger.Warn("failed to get attachment upload status", zap.Uint("upload_id", attachment.UploadID), zap.Error(err))
+		return result
+	}
+
+	result.Status = state.Status
+	result.Completed = state.Completed
+	return result
+}
+
+// listAttachments handles GET /api/items/{id}/attachments
+// Returns the files attached to an item with the status of their upload workflows, read from
+// their recorded progress in a single query
+func (a *API) listAttachments(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		a.writeError(w, badRequest(err))
+		return
+	}
+
+	// The route is public, so the actor may be unknown
+	actor, _ := a.actorFromRequest(r)
+
+	attachments, err := a.itemSvc.ListAttachments(actor, id)
+	if err != nil {
+		a.writeError(w, err)
+		return
+	}
+
+	proto, ok := core.GetProtocol(internal.PLUGIN_NAME).(*protocol.Protocol)
+	if !ok {
+		a.writeError(w, errors.New("protocol not found"))
+		return
+	}
+
+	uploadIDs := make([]string, 0, len(attachments))
+	for _, attachment := range attachments {
+		uploadIDs = append(uploadIDs, strconv.FormatUint(uint64(attachment.UploadID), 10))
+	}
+
+	states, err := proto.GetUploadStatuses(uploadIDs)
+	if err != nil {
+		// Missing statuses shouldn't hide the attachments themselves
+		a.logger.Warn("failed to get attachment upload statuses", zap.Error(err))
+	}
+
+	response := messages.ListAttachmentsResponse{
+		Attachments: make([]messages.Attachment, 0, len(attachments)),
+	}
+	for n, attachment := range attachments {
+		state, ok := states[uploadIDs[n]]
+		if !ok {
+			// Uploads stored before their progress was recorded report their workflow status
+			response.Attachments = append(response.Attachments, a.attachmentWithStatus(proto, attachment))
+			continue
+		}
+
+		response.Attachments = append(response.Attachments, messages.Attachment{
+			ItemAttachment: attachment,
+			Status:         state.Status,
+			Completed:      state.Completed,
+		})
+	}
+
+	ctx.Encode(response)
+}
+
+// attachFile handles POST /api/items/{id}/attachments
+// Uploads a raw or multipart body through the upload workflow and attaches it to the item.
+// The file name is taken from the name parameter or the Content-Disposition header.
+func (a *API) attachFile(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		a.writeError(w, badRequest(err))
+		return
+	}
+
+	actor, err := a.actorFromRequest(r)
+	if err != nil {
+		a.writeError(w, unauthorized(err))
+		return
+	}
+
+	request := messages.AttachmentRequest{Name: attachmentNameFromRequest(r)}
+	if !a.validateRequest(w, &request) {
+		return
+	}
+
+	proto, ok := core.GetProtocol(internal.PLUGIN_NAME).(*protocol.Protocol)
+	if !ok {
+		a.writeError(w, errors.New("protocol not found"))
+		return
+	}
+
+	attachment, err := a.itemSvc.AddAttachment(actor, id, &attachmentUpload{
+		w:      w,
+		r:      r,
+		api:    a,
+		proto:  proto,
+		userID: actor.UserID,
+		name:   request.Name,
+	})
+	if err != nil {
+		a.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/uploads/%d", attachment.UploadID))
+	a.writeJSON(w, http.StatusAccepted, a.attachmentWithStatus(proto, *attachment))
+}
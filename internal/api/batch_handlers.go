@@ -0,0 +1,127 @@
+// Package api implements the batch item handler for the template plugin
+package api
+
+import (
+	"fmt"
+	"go.lumeweb.com/portal-plugin-template/internal"
+	"go.lumeweb.com/portal-plugin-template/internal/api/messages"
+	pluginConfig "go.lumeweb.com/portal-plugin-template/internal/config"
+	"go.lumeweb.com/portal-plugin-template/internal/service"
+	"go.lumeweb.com/portal-plugin-template/internal/templates"
+	"go.lumeweb.com/portal/core"
+	"net/http"
+)
+
+// validateBatch checks the size of a batch and validates each of its operations.
+// Failures are keyed by the operation's position, such as operations[2].name.
+func (a *API) validateBatch(request *messages.BatchRequest) messages.ValidationErrors {
+	errs := messages.ValidationErrors{}
+
+	maxSize := a.config.GetAPI(internal.PLUGIN_NAME).(*pluginConfig.APIConfig).MaxBatchSize
+	switch {
+	case len(request.Operations) == 0:
+		errs["operations"] = "is required"
+		return errs
+	case maxSize > 0 && len(request.Operations) > maxSize:
+		errs["operations"] = fmt.Sprintf("must contain at most %d operations", maxSize)
+		return errs
+	}
+
+	limits := a.validationLimits()
+	for i := range request.Operations {
+		op := &request.Operations[i]
+
+		for field, msg := range messages.Validate(op, limits) {
+			errs[fmt.Sprintf("operations[%d].%s", i, field)] = msg
+		}
+
+		if op.Op != service.BatchOpCreate && op.ID == 0 {
+			errs[fmt.Sprintf("operations[%d].id", i)] = "is required"
+		}
+	}
+
+	return errs
+}
+
+// batchItems handles POST /api/items/batch
+// Runs a list of create, update and delete operations and reports the outcome of each.
+// A single summary email is sent instead of one per created item.
+func (a *API) batchItems(w http.ResponseWriter, r *http.Request) {
+	actor, err := a.actorFromRequest(r)
+	if err != nil {
+		a.writeError(w, unauthorized(err))
+		return
+	}
+
+	var request messages.BatchRequest
+	if !a.decodeRequest(w, r, &request) {
+		return
+	}
+
+	if errs := a.validateBatch(&request); len(errs) > 0 {
+		a.writeError(w, errs)
+		return
+	}
+
+	operations := make([]service.BatchOperation, 0, len(request.Operations))
+	for _, op := range request.Operations {
+		operations = append(operations, service.BatchOperation{
+			Op:          op.Op,
+			ID:          op.ID,
+			Version:     op.Version,
+			Name:        op.Name,
+			Description: op.Description,
+			Visibility:  op.Visibility,
+			Metadata:    op.Metadata,
+		})
+	}
+
+	// In atomic mode a failure rolls back the whole batch and is reported
+	// with the status of the operation that caused it
+	results, err := a.itemSvc.ExecuteBatch(actor, operations, !request.ContinueOnError)
+	if err != nil {
+		a.writeError(w, err)
+		return
+	}
+
+	response := messages.BatchResponse{
+		Results: make([]messages.BatchOperationResult, 0, len(results)),
+	}
+	counts := map[string]int{}
+
+	for i, result := range results {
+		entry := messages.BatchOperationResult{
+			Index: i,
+			Op:    operations[i].Op,
+			Item:  result.Item,
+		}
+
+		if result.Err != nil {
+			status, detail := a.describeError(result.Err)
+			entry.Status = status
+			entry.Error = &detail
+			response.Failed++
+		} else {
+			entry.Status = http.StatusOK
+			if operations[i].Op == service.BatchOpCreate {
+				entry.Status = http.StatusCreated
+			}
+			counts[operations[i].Op]++
+			response.Succeeded++
+		}
+
+		response.Results = append(response.Results, entry)
+	}
+
+	if response.Succeeded > 0 {
+		a.notifyUser(actor.UserID, templates.MAILER_TPL_ITEMS_BATCH, core.MailerTemplateData{
+			"Succeeded": response.Succeeded,
+			"Created":   counts[service.BatchOpCreate],
+			"Updated":   counts[service.BatchOpUpdate],
+			"Deleted":   counts[service.BatchOpDelete],
+			"Failed":    response.Failed,
+		})
+	}
+
+	a.writeJSON(w, http.StatusOK, response)
+}
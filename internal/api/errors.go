@@ -0,0 +1,131 @@
+// Package api implements the mapping of errors onto HTTP responses
+package api
+
+import (
+	"errors"
+	"go.lumeweb.com/portal-plugin-template/internal/api/messages"
+	"go.lumeweb.com/portal-plugin-template/internal/protocol"
+	"go.lumeweb.com/portal-plugin-template/internal/service"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// statusError attaches an HTTP status and error code to an error raised by a handler
+type statusError struct {
+	status int
+	code   string
+	err    error
+}
+
+func (e *statusError) Error() string {
+	return e.err.Error()
+}
+
+func (e *statusError) Unwrap() error {
+	return e.err
+}
+
+// badRequest marks err as a malformed request
+func badRequest(err error) error {
+	return &statusError{http.StatusBadRequest, messages.ErrorCodeBadRequest, err}
+}
+
+// unauthorized marks err as a missing or invalid authentication
+func unauthorized(err error) error {
+	return &statusError{http.StatusUnauthorized, messages.ErrorCodeUnauthorized, err}
+}
+
+// notFound marks err as a missing resource
+func notFound(err error) error {
+	return &statusError{http.StatusNotFound, messages.ErrorCodeNotFound, err}
+}
+
+// errorMappings maps service sentinel errors to HTTP statuses and error codes
+var errorMappings = []struct {
+	target error
+	status int
+	code   string
+}{
+	{service.ErrNotFound, http.StatusNotFound, messages.ErrorCodeNotFound},
+	{service.ErrForbidden, http.StatusForbidden, messages.ErrorCodeForbidden},
+	{service.ErrConflict, http.StatusConflict, messages.ErrorCodeConflict},
+	{service.ErrPreconditionFailed, http.StatusPreconditionFailed, messages.ErrorCodePreconditionFailed},
+	{service.ErrValidation, http.StatusBadRequest, messages.ErrorCodeValidationFailed},
+	{protocol.ErrSizeMismatch, http.StatusBadRequest, messages.ErrorCodeBadRequest},
+}
+
+// writeError writes err as a JSON error envelope, choosing the status code and
+// error code from the error's type. Unrecognised errors are logged and reported
+// as internal errors without exposing their message.
+func (a *API) writeError(w http.ResponseWriter, err error) {
+	status, detail := a.describeError(err)
+	a.writeJSON(w, status, messages.ErrorResponse{Error: detail})
+}
+
+// describeError maps an error onto its HTTP status and error detail
+func (a *API) describeError(err error) (int, messages.ErrorDetail) {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.status, messages.ErrorDetail{Code: statusErr.code, Message: err.Error()}
+	}
+
+	var validationErrs messages.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return http.StatusBadRequest, messages.ErrorDetail{
+			Code:    messages.ErrorCodeValidationFailed,
+			Message: err.Error(),
+			Fields:  validationErrs,
+		}
+	}
+
+	var metadataErr *service.MetadataError
+	if errors.As(err, &metadataErr) {
+		// Report failures against the request field, such as metadata.color
+		fields := messages.ValidationErrors{}
+		for path, msg := range metadataErr.Fields {
+			if path == "" {
+				fields["metadata"] = msg
+			} else {
+				fields["metadata."+path] = msg
+			}
+		}
+
+		return http.StatusBadRequest, messages.ErrorDetail{
+			Code:    messages.ErrorCodeValidationFailed,
+			Message: err.Error(),
+			Fields:  fields,
+		}
+	}
+
+	var quotaErr *service.QuotaError
+	if errors.As(err, &quotaErr) {
+		// The portal wide limit is a hard capacity limit, the per-user quota
+		// is reported as the user having created too many items
+		status := http.StatusTooManyRequests
+		if quotaErr.Scope == service.QuotaScopeGlobal {
+			status = http.StatusForbidden
+		}
+
+		return status, messages.ErrorDetail{
+			Code:    messages.ErrorCodeQuotaExceeded,
+			Message: err.Error(),
+			Quota: &messages.QuotaError{
+				Scope: quotaErr.Scope,
+				Usage: quotaErr.Usage,
+				Limit: quotaErr.Limit,
+			},
+		}
+	}
+
+	for _, mapping := range errorMappings {
+		if errors.Is(err, mapping.target) {
+			return mapping.status, messages.ErrorDetail{Code: mapping.code, Message: err.Error()}
+		}
+	}
+
+	a.logger.Error("unhandled API error", zap.Error(err))
+	return http.StatusInternalServerError, messages.ErrorDetail{
+		Code:    messages.ErrorCodeInternal,
+		Message: http.StatusText(http.StatusInternalServerError),
+	}
+}
@@ -0,0 +1,62 @@
+// Package api implements entity tag handling for optimistic concurrency control
+package api
+
+import (
+	"fmt"
+	"go.lumeweb.com/portal-plugin-template/internal/db/models"
+	"go.lumeweb.com/portal-plugin-template/internal/service"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// itemETag returns the strong entity tag for the current version of an item
+func itemETag(item *models.Item) string {
+	return fmt.Sprintf("\"%d\"", item.Version)
+}
+
+// setETag sets the ETag header to the item's current version
+func setETag(w http.ResponseWriter, item *models.Item) {
+	w.Header().Set("ETag", itemETag(item))
+}
+
+// versionFromRequest returns the item version the client expects from the If-Match header.
+// It returns 0 when the header is absent or "*", meaning any version is accepted.
+// Weak tags never match, as RFC 9110 requires strong comparison for If-Match, and a
+// malformed header is reported as a failed precondition.
+func versionFromRequest(r *http.Request) (uint, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	// Every item has a single current version, so only a single tag can ever match
+	if len(header) < 2 || strings.Contains(header, ",") || !strings.HasPrefix(header, "\"") || !strings.HasSuffix(header, "\"") {
+		return 0, fmt.Errorf("%w: If-Match must be a single strong entity tag", service.ErrPreconditionFailed)
+	}
+
+	version, err := strconv.ParseUint(header[1:len(header)-1], 10, 64)
+	if err != nil || version == 0 {
+		return 0, fmt.Errorf("%w: unknown entity tag %s", service.ErrPreconditionFailed, header)
+	}
+
+	return uint(version), nil
+}
+
+// notModified reports whether the If-None-Match header matches the item's current ETag
+func notModified(r *http.Request, item *models.Item) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	etag := itemETag(item)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
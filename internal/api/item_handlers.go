@@ -2,9 +2,11 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/gorilla/mux"
+	tusd "github.com/tus/tusd/v2/pkg/handler"
 	"go.lumeweb.com/httputil"
 	"go.lumeweb.com/portal-plugin-template/internal"
 	"go.lumeweb.com/portal-plugin-template/internal/api/messages"
@@ -18,6 +20,8 @@ import (
 	"go.uber.org/zap"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // registerItemHandlers sets up all item-related routes and their access control.
@@ -36,9 +40,35 @@ func (a *API) registerItemHandlers(router *mux.Router, accessSvc core.AccessServ
 		{"/api/items", "POST", a.createItem, core.ACCESS_USER_ROLE},
 		{"/api/items/{id:[0-9]+}", "GET", a.getItem, ""},
 		{"/api/items/{id:[0-9]+}", "PUT", a.updateItem, core.ACCESS_USER_ROLE},
+		{"/api/items/{id:[0-9]+}", "PATCH", a.patchItem, core.ACCESS_USER_ROLE},
 		{"/api/items/{id:[0-9]+}", "DELETE", a.deleteItem, core.ACCESS_USER_ROLE},
 		{"/api/items/search", "GET", a.searchItems, ""},
 		{"/api/items/protected", "GET", a.listProtectedItems, core.ACCESS_USER_ROLE},
+		{"/api/items/trash", "GET", a.listTrash, core.ACCESS_USER_ROLE},
+		{"/api/items/batch", "POST", a.batchItems, core.ACCESS_USER_ROLE},
+		{"/api/items/export", "GET", a.exportItems, core.ACCESS_USER_ROLE},
+		{"/api/items/import", "POST", a.importItems, core.ACCESS_USER_ROLE},
+		{"/api/imports/{id:[0-9]+}", "GET", a.getImportStatus, core.ACCESS_USER_ROLE},
+		{"/api/items/{id:[0-9]+}/restore", "POST", a.restoreItem, core.ACCESS_USER_ROLE},
+		{"/api/items/{id:[0-9]+}/tags", "PUT", a.setItemTags, core.ACCESS_USER_ROLE},
+		{"/api/items/{id:[0-9]+}/attachments", "GET", a.listAttachments, ""},
+		{"/api/items/{id:[0-9]+}/attachments", "POST", a.attachFile, core.ACCESS_USER_ROLE},
+		{"/api/uploads", "POST", a.uploadFile, core.ACCESS_USER_ROLE},
+		{"/api/uploads/{id}/events", "GET", a.getUploadEvents, core.ACCESS_USER_ROLE},
+		{protocol.TusBasePath, "POST", a.tusUpload((*tusd.UnroutedHandler).PostFile), core.ACCESS_USER_ROLE},
+		{protocol.TusBasePath + "{id}", "HEAD", a.tusUpload((*tusd.UnroutedHandler).HeadFile), core.ACCESS_USER_ROLE},
+		{protocol.TusBasePath + "{id}", "PATCH", a.tusUpload((*tusd.UnroutedHandler).PatchFile), core.ACCESS_USER_ROLE},
+		{protocol.TusBasePath + "{id}", "DELETE", a.tusUpload((*tusd.UnroutedHandler).DelFile), core.ACCESS_USER_ROLE},
+		{"/api/objects/{hash}", "GET", a.getObject, ""},
+		{"/api/items/{id:[0-9]+}/revisions", "GET", a.listRevisions, ""},
+		{"/api/items/{id:[0-9]+}/revisions/{rev:[0-9]+}", "GET", a.getRevision, ""},
+		{"/api/items/{id:[0-9]+}/revisions/{rev:[0-9]+}/revert", "POST", a.revertItem, core.ACCESS_USER_ROLE},
+		{"/api/tags", "GET", a.listTags, ""},
+		{"/api/tags", "POST", a.createTag, core.ACCESS_ADMIN_ROLE},
+		{"/api/tags/counts", "GET", a.countTags, ""},
+		{"/api/tags/{id:[0-9]+}", "PUT", a.updateTag, core.ACCESS_ADMIN_ROLE},
+		{"/api/tags/{id:[0-9]+}", "DELETE", a.deleteTag, core.ACCESS_ADMIN_ROLE},
+		{"/api/quota", "GET", a.getQuota, core.ACCESS_USER_ROLE},
 	}
 
 	// Add upload status route
@@ -55,13 +85,17 @@ func (a *API) registerItemHandlers(router *mux.Router, accessSvc core.AccessServ
 	for _, route := range routes {
 		r := router.HandleFunc(route.Path, route.Handler).Methods(route.Method)
 
+		// Public routes let anonymous requests through, but still identify users sending a token
+		// so owners and admins can see their private items
+		r.Use(middleware.AuthMiddleware(middleware.AuthMiddlewareOptions{
+			Context:      a.ctx,
+			Purpose:      core.JWTPurposeLogin,
+			EmptyAllowed: route.Access == "",
+		}))
 		if route.Access != "" {
-			r.Use(middleware.AuthMiddleware(middleware.AuthMiddlewareOptions{
-				Context: a.ctx,
-				Purpose: core.JWTPurposeLogin,
-			}))
 			r.Use(middleware.AccessMiddleware(a.ctx))
 		}
+		r.Use(a.resolveActor)
 
 		if err := accessSvc.RegisterRoute(a.Subdomain(), route.Path, route.Method, route.Access); err != nil {
 			a.logger.Error("failed to register route", zap.Error(err))
@@ -69,11 +103,43 @@ func (a *API) registerItemHandlers(router *mux.Router, accessSvc core.AccessServ
 	}
 }
 
-// listItems handles GET /api/items
-// Returns a paginated list of items with total count
-func (a *API) listItems(w http.ResponseWriter, r *http.Request) {
-	ctx := httputil.Context(r, w)
+// actorContextKey is the request context key holding the acting user
+type actorContextKey struct{}
+
+// withActor returns a copy of ctx carrying the acting user
+func withActor(ctx context.Context, actor *service.Actor) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actor)
+}
+
+// resolveActor is a middleware building the acting user for the item service from the
+// authenticated user, if any. Anonymous requests on public routes pass through without one.
+// Users holding the admin role are flagged so the service lets them bypass ownership checks.
+func (a *API) resolveActor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserFromContext(r.Context())
+		if err != nil || userID == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(withActor(r.Context(), service.UserActor(a.ctx, userID))))
+	})
+}
 
+// actorFromRequest returns the acting user of the request, or an error for anonymous requests
+func (a *API) actorFromRequest(r *http.Request) (*service.Actor, error) {
+	actor, ok := r.Context().Value(actorContextKey{}).(*service.Actor)
+	if !ok || actor == nil {
+		return nil, errors.New("authentication required")
+	}
+
+	return actor, nil
+}
+
+// paginationFromRequest reads the page, limit and cursor query parameters,
+// falling back to the first page and the configured page size.
+// Passing a cursor parameter, even an empty one, selects cursor mode.
+func (a *API) paginationFromRequest(r *http.Request) *service.Pagination {
 	pagination := &service.Pagination{
 		Page:  1,
 		Limit: a.config.GetAPI(internal.PLUGIN_NAME).(*pluginConfig.APIConfig).ItemsPerPage,
@@ -91,125 +157,319 @@ func (a *API) listItems(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	items, total, err := a.itemSvc.ListItems(pagination)
-	if err != nil {
-		_ = ctx.Error(err, http.StatusInternalServerError)
-		return
+	if r.URL.Query().Has("cursor") {
+		pagination.UseCursor = true
+		pagination.Cursor = r.URL.Query().Get("cursor")
+	}
+
+	return pagination
+}
+
+// filterFromRequest reads the sort, order and filter query parameters.
+// Sort fields are validated by the item service, timestamps must be RFC 3339.
+// The tag parameter may be repeated to require several tags, and metadata.<key>
+// parameters match items whose metadata holds the given value at that key.
+func filterFromRequest(r *http.Request) (*service.ItemFilter, error) {
+	query := r.URL.Query()
+
+	filter := &service.ItemFilter{
+		Sort:       query.Get("sort"),
+		Order:      query.Get("order"),
+		NamePrefix: query.Get("name_prefix"),
+		Tags:       query["tag"],
 	}
 
+	times := []struct {
+		Param string
+		Dest  **time.Time
+	}{
+		{"created_after", &filter.CreatedAfter},
+		{"created_before", &filter.CreatedBefore},
+		{"updated_after", &filter.UpdatedAfter},
+		{"updated_before", &filter.UpdatedBefore},
+	}
+
+	for _, t := range times {
+		value := query.Get(t.Param)
+		if value == "" {
+			continue
+		}
+
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", t.Param, err)
+		}
+		*t.Dest = &parsed
+	}
+
+	for param, values := range query {
+		key, ok := strings.CutPrefix(param, "metadata.")
+		if !ok {
+			continue
+		}
+		if !service.ValidMetadataKey(key) {
+			return nil, fmt.Errorf("invalid metadata key %q", key)
+		}
+
+		if filter.Metadata == nil {
+			filter.Metadata = map[string]string{}
+		}
+		filter.Metadata[key] = values[0]
+	}
+
+	return filter, nil
+}
+
+// listItemsResponse builds the list response for a page of items
+func listItemsResponse(items []models.Item, total int64, pagination *service.Pagination) messages.ListItemsResponse {
 	response := messages.ListItemsResponse{
 		Items: items,
-		Total: total,
-		Page:  pagination.Page,
 		Limit: pagination.Limit,
 	}
-	ctx.Encode(response)
+
+	if pagination.UseCursor {
+		response.NextCursor = pagination.NextCursor
+		response.PrevCursor = pagination.PrevCursor
+	} else {
+		response.Total = total
+		response.Page = pagination.Page
+	}
+
+	return response
+}
+
+// listItems handles GET /api/items
+// Returns a sorted, filtered and paginated list of public items
+func (a *API) listItems(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	pagination := a.paginationFromRequest(r)
+
+	filter, err := filterFromRequest(r)
+	if err != nil {
+		a.writeError(w, badRequest(err))
+		return
+	}
+
+	items, total, err := a.itemSvc.ListItems(nil, filter, pagination)
+	if err != nil {
+		a.writeError(w, err)
+		return
+	}
+
+	ctx.Encode(listItemsResponse(items, total, pagination))
 }
 
 // createItem handles POST /api/items
-// Creates a new item from the provided request data
+// Creates a new item from the provided request data and returns it with its location
 func (a *API) createItem(w http.ResponseWriter, r *http.Request) {
-	ctx := httputil.Context(r, w)
+	actor, err := a.actorFromRequest(r)
+	if err != nil {
+		a.writeError(w, unauthorized(err))
+		return
+	}
 
 	var request messages.CreateItemRequest
-	if err := ctx.Decode(&request); err != nil {
+	if !a.decodeRequest(w, r, &request) {
 		return
 	}
 
-	item, err := a.itemSvc.CreateItem(request.Name, request.Description)
+	if !a.validateRequest(w, &request) {
+		return
+	}
+
+	item, err := a.itemSvc.CreateItem(actor, request.Name, request.Description, request.Visibility, request.Metadata)
 	if err != nil {
-		_ = ctx.Error(err, http.StatusInternalServerError)
-		return
-	}
-
-	// Get the authenticated user from the request context
-	if userID, err := middleware.GetUserFromContext(r.Context()); err == nil {
-		// Get user service to lookup user details
-		userSvc := core.GetService[core.UserService](a.ctx, core.USER_SERVICE)
-		if exists, user, err := userSvc.AccountExists(userID); err == nil && exists {
-			// Send notification email
-			mailerSvc := a.ctx.Service(core.MAILER_SERVICE).(core.MailerService)
-
-			templateData := core.MailerTemplateData{
-				"UserName":    fmt.Sprintf("%s %s", user.FirstName, user.LastName),
-				"PortalName":  "Portal",
-				"Name":        item.Name,
-				"Description": item.Description,
-				"ItemURL":     fmt.Sprintf("https://%s/items/%d", a.Subdomain(), item.ID),
-			}
-
-			err = mailerSvc.TemplateSend(templates.MAILER_TPL_ITEM_CREATED, templateData, templateData, user.Email)
-			if err != nil {
-				a.logger.Error("failed to send item created email", zap.Error(err))
-				// Don't return error to client, item was still created successfully
-			}
-		}
+		a.writeError(w, err)
+		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	a.notifyUser(actor.UserID, templates.MAILER_TPL_ITEM_CREATED, core.MailerTemplateData{
+		"Name":        item.Name,
+		"Description": item.Description,
+		"ItemURL":     fmt.Sprintf("https://%s/items/%d", a.Subdomain(), item.ID),
+	})
+
+	w.Header().Set("Location", fmt.Sprintf("/api/items/%d", item.ID))
+	setETag(w, item)
+	a.writeJSON(w, http.StatusCreated, item)
+}
+
+// notifyUser sends a templated email to the given user, filling in their name and the
+// portal name alongside the supplied template data. Failures are logged rather than
+// returned, as notifications never fail the request that triggered them.
+func (a *API) notifyUser(userID uint, template string, data core.MailerTemplateData) {
+	// Get user service to lookup the user's details
+	userSvc := core.GetService[core.UserService](a.ctx, core.USER_SERVICE)
+	exists, user, err := userSvc.AccountExists(userID)
+	if err != nil || !exists {
+		return
+	}
+
+	data["UserName"] = fmt.Sprintf("%s %s", user.FirstName, user.LastName)
+	data["PortalName"] = "Portal"
+
+	mailerSvc := a.ctx.Service(core.MAILER_SERVICE).(core.MailerService)
+	if err := mailerSvc.TemplateSend(template, data, data, user.Email); err != nil {
+		a.logger.Error("failed to send notification email", zap.String("template", template), zap.Error(err))
+	}
 }
 
 // getItem handles GET /api/items/{id}
-// Retrieves a single item by its ID
+// Retrieves a single item by its ID, tagged with an ETag for its current version
 func (a *API) getItem(w http.ResponseWriter, r *http.Request) {
 	ctx := httputil.Context(r, w)
 	vars := mux.Vars(r)
 
 	id, err := strconv.ParseUint(vars["id"], 10, 64)
 	if err != nil {
-		_ = ctx.Error(err, http.StatusBadRequest)
+		a.writeError(w, badRequest(err))
 		return
 	}
 
-	_, err = a.itemSvc.GetItem(id)
+	// The route is public, so the actor may be unknown
+	actor, _ := a.actorFromRequest(r)
+
+	item, err := a.itemSvc.GetItem(actor, id)
 	if err != nil {
-		_ = ctx.Error(err, http.StatusNotFound)
+		a.writeError(w, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	setETag(w, item)
+	if notModified(r, item) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	ctx.Encode(item)
 }
 
 // updateItem handles PUT /api/items/{id}
-// Updates an existing item with the provided data
+// Updates an existing item with the provided data and returns the updated item.
+// An If-Match header makes the update conditional on the item's current ETag.
 func (a *API) updateItem(w http.ResponseWriter, r *http.Request) {
 	ctx := httputil.Context(r, w)
 	vars := mux.Vars(r)
 
 	id, err := strconv.ParseUint(vars["id"], 10, 64)
 	if err != nil {
-		_ = ctx.Error(err, http.StatusBadRequest)
+		a.writeError(w, badRequest(err))
+		return
+	}
+
+	actor, err := a.actorFromRequest(r)
+	if err != nil {
+		a.writeError(w, unauthorized(err))
+		return
+	}
+
+	version, err := versionFromRequest(r)
+	if err != nil {
+		a.writeError(w, err)
 		return
 	}
 
 	var request messages.UpdateItemRequest
-	if err := ctx.Decode(&request); err != nil {
+	if !a.decodeRequest(w, r, &request) {
 		return
 	}
 
-	err = a.itemSvc.UpdateItem(id, request.Name, request.Description)
+	if !a.validateRequest(w, &request) {
+		return
+	}
+
+	item, err := a.itemSvc.UpdateItem(actor, id, version, request.Name, request.Description, request.Visibility, request.Metadata)
 	if err != nil {
-		_ = ctx.Error(err, http.StatusInternalServerError)
+		a.writeError(w, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	setETag(w, item)
+	ctx.Encode(item)
+}
+
+// patchItem handles PATCH /api/items/{id}
+// Applies a JSON Merge Patch to an existing item and returns the updated item.
+// An If-Match header makes the patch conditional on the item's current ETag.
+func (a *API) patchItem(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	vars := mux.Vars(r)
+
+	id, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		a.writeError(w, badRequest(err))
+		return
+	}
+
+	actor, err := a.actorFromRequest(r)
+	if err != nil {
+		a.writeError(w, unauthorized(err))
+		return
+	}
+
+	version, err := versionFromRequest(r)
+	if err != nil {
+		a.writeError(w, err)
+		return
+	}
+
+	var request messages.PatchItemRequest
+	if !a.decodeRequest(w, r, &request) {
+		return
+	}
+
+	if !a.validateRequest(w, &request) {
+		return
+	}
+
+	item, err := a.itemSvc.PatchItem(actor, id, version, &service.ItemPatch{
+		Name:        request.Name.Ptr(),
+		Description: request.Description.Ptr(),
+		Visibility:  request.Visibility.Ptr(),
+		Metadata:    request.Metadata.Ptr(),
+	})
+	if err != nil {
+		a.writeError(w, err)
+		return
+	}
+
+	setETag(w, item)
+	ctx.Encode(item)
 }
 
 // deleteItem handles DELETE /api/items/{id}
-// Removes an item from the database
+// Moves an item to the trash, or permanently removes it when purge=true is passed by an admin.
+// An If-Match header makes the deletion conditional on the item's current ETag.
 func (a *API) deleteItem(w http.ResponseWriter, r *http.Request) {
-	ctx := httputil.Context(r, w)
 	vars := mux.Vars(r)
 
 	id, err := strconv.ParseUint(vars["id"], 10, 64)
 	if err != nil {
-		_ = ctx.Error(err, http.StatusBadRequest)
+		a.writeError(w, badRequest(err))
+		return
+	}
+
+	actor, err := a.actorFromRequest(r)
+	if err != nil {
+		a.writeError(w, unauthorized(err))
+		return
+	}
+
+	version, err := versionFromRequest(r)
+	if err != nil {
+		a.writeError(w, err)
 		return
 	}
 
-	if err := a.itemSvc.DeleteItem(id); err != nil {
-		_ = ctx.Error(err, http.StatusInternalServerError)
+	purge, _ := strconv.ParseBool(r.URL.Query().Get("purge"))
+	if purge {
+		err = a.itemSvc.PurgeItem(actor, id, version)
+	} else {
+		err = a.itemSvc.DeleteItem(actor, id, version)
+	}
+	if err != nil {
+		a.writeError(w, err)
 		return
 	}
 
@@ -217,73 +477,105 @@ func (a *API) deleteItem(w http.ResponseWriter, r *http.Request) {
 }
 
 // searchItems handles GET /api/items/search
-// Performs a text search on item names and descriptions
+// Performs a ranked full-text search on public item names and descriptions,
+// optionally restricted to items carrying every requested tag
 func (a *API) searchItems(w http.ResponseWriter, r *http.Request) {
 	ctx := httputil.Context(r, w)
 
 	query := r.URL.Query().Get("q")
 	if query == "" {
-		_ = ctx.Error(errors.New("search query required"), http.StatusBadRequest)
+		a.writeError(w, badRequest(errors.New("search query required")))
 		return
 	}
 
-	limit := a.config.GetAPI(internal.PLUGIN_NAME).(*pluginConfig.APIConfig).SearchLimit
-	items, total, err := a.itemSvc.SearchItems(query, limit)
+	pagination := a.paginationFromRequest(r)
+
+	// Never return more than the configured search limit in a single page
+	if limit := a.config.GetAPI(internal.PLUGIN_NAME).(*pluginConfig.APIConfig).SearchLimit; pagination.Limit > limit {
+		pagination.Limit = limit
+	}
+
+	results, total, err := a.itemSvc.SearchItems(query, r.URL.Query()["tag"], pagination)
 	if err != nil {
-		_ = ctx.Error(err, http.StatusInternalServerError)
+		a.writeError(w, err)
 		return
 	}
 
+	items := make([]messages.SearchResult, 0, len(results))
+	for _, result := range results {
+		items = append(items, messages.SearchResult{
+			Item:    result.Item,
+			Score:   result.Score,
+			Snippet: result.Snippet,
+		})
+	}
+
 	response := messages.SearchItemsResponse{
 		Items: items,
 		Total: total,
+		Page:  pagination.Page,
+		Limit: pagination.Limit,
 	}
 	ctx.Encode(response)
 }
 
 // listProtectedItems handles GET /api/items/protected
-// Demonstrates a protected endpoint requiring authentication
+// Returns a paginated list of the authenticated user's private items
 func (a *API) listProtectedItems(w http.ResponseWriter, r *http.Request) {
 	ctx := httputil.Context(r, w)
 
-	var items []models.Item
-	if err := a.ctx.DB().Find(&items).Error; err != nil {
-		_ = ctx.Error(err, http.StatusInternalServerError)
+	actor, err := a.actorFromRequest(r)
+	if err != nil {
+		a.writeError(w, unauthorized(err))
 		return
 	}
 
-	response := messages.ListItemsResponse{
-		Items: items,
-		Total: int64(len(items)),
-		Page:  1,
-		Limit: len(items),
+	pagination := a.paginationFromRequest(r)
+
+	items, total, err := a.itemSvc.ListPrivateItems(actor, pagination)
+	if err != nil {
+		a.writeError(w, err)
+		return
 	}
-	ctx.Encode(response)
+
+	ctx.Encode(listItemsResponse(items, total, pagination))
 }
 
 // getUploadStatus handles GET /api/uploads/{id}
-// Returns the current status of an upload operation
+// Returns the current phase and progress of an upload started by the authenticated user
 func (a *API) getUploadStatus(w http.ResponseWriter, r *http.Request) {
 	ctx := httputil.Context(r, w)
 	vars := mux.Vars(r)
 	uploadID := vars["id"]
 
 	if uploadID == "" {
-		_ = ctx.Error(errors.New("upload ID required"), http.StatusBadRequest)
+		a.writeError(w, badRequest(errors.New("upload ID required")))
+		return
+	}
+
+	actor, err := a.actorFromRequest(r)
+	if err != nil {
+		a.writeError(w, unauthorized(err))
 		return
 	}
 
 	// Get protocol service
 	proto := core.GetProtocol(internal.PLUGIN_NAME).(*protocol.Protocol)
 	if proto == nil {
-		_ = ctx.Error(errors.New("protocol not found"), http.StatusInternalServerError)
+		a.writeError(w, errors.New("protocol not found"))
 		return
 	}
 
 	// Get upload state
 	state, err := proto.GetUploadStatus(uploadID)
 	if err != nil {
-		_ = ctx.Error(err, http.StatusNotFound)
+		a.writeError(w, notFound(err))
+		return
+	}
+
+	// Uploads of other users are reported as missing rather than forbidden
+	if !actor.Admin && state.UserID != actor.UserID {
+		a.writeError(w, notFound(fmt.Errorf("upload %s not found", uploadID)))
 		return
 	}
 
@@ -293,11 +585,17 @@ func (a *API) getUploadStatus(w http.ResponseWriter, r *http.Request) {
 			ID:        state.ID,
 			Size:      state.Size,
 			Uploaded:  state.Uploaded,
+			Phase:     state.Phase,
+			Processed: state.Processed,
+			Error:     state.Error,
 			Started:   state.Started,
 			Completed: state.Completed,
-			Hash:      state.Hash.Multihash().B58String(),
 		},
 	}
+	// The hash is only known once the data has been received
+	if state.Hash != nil {
+		response.State.Hash = proto.EncodeFileName(state.Hash)
+	}
 
 	ctx.Encode(response)
 }
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"github.com/gorilla/mux"
+	"go.lumeweb.com/portal-plugin-template/internal/db/models"
+	"go.lumeweb.com/portal-plugin-template/internal/service"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// stubItemService returns a fixed result from GetItem and records the actor it was called with.
+// Visibility rules are the item service's concern, so the stub doesn't apply any.
+type stubItemService struct {
+	service.ItemService
+	item  *models.Item
+	err   error
+	actor *service.Actor
+	calls int
+}
+
+func (s *stubItemService) GetItem(actor *service.Actor, _ uint64) (*models.Item, error) {
+	s.actor = actor
+	s.calls++
+	return s.item, s.err
+}
+
+func TestGetItem(t *testing.T) {
+	item := &models.Item{Model: gorm.Model{ID: 1}, OwnerID: 7, Name: "private", Visibility: models.VisibilityPrivate, Version: 1}
+
+	tests := []struct {
+		name  string
+		id    string
+		actor *service.Actor
+		err   error
+		want  int
+	}{
+		{name: "user", id: "1", actor: &service.Actor{UserID: 7}, want: http.StatusOK},
+		{name: "admin", id: "1", actor: &service.Actor{UserID: 1, Admin: true}, want: http.StatusOK},
+		{name: "anonymous", id: "1", want: http.StatusOK},
+		{name: "not found", id: "1", actor: &service.Actor{UserID: 8}, err: service.ErrNotFound, want: http.StatusNotFound},
+		{name: "forbidden", id: "1", actor: &service.Actor{UserID: 8}, err: service.ErrForbidden, want: http.StatusForbidden},
+		{name: "invalid ID", id: "item", want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &stubItemService{item: item, err: tt.err}
+			a := &API{itemSvc: svc}
+
+			r := httptest.NewRequest(http.MethodGet, "/api/items/"+tt.id, nil)
+			if tt.actor != nil {
+				r = r.WithContext(withActor(r.Context(), tt.actor))
+			}
+			r = mux.SetURLVars(r, map[string]string{"id": tt.id})
+
+			// Requests without a portal user pass through resolveActor untouched
+			w := httptest.NewRecorder()
+			a.resolveActor(http.HandlerFunc(a.getItem)).ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("GET /api/items/%s status = %d, want %d: %s", tt.id, w.Code, tt.want, w.Body)
+			}
+
+			if tt.want == http.StatusBadRequest {
+				if svc.calls != 0 {
+					t.Error("GetItem() called for an invalid item ID")
+				}
+				return
+			}
+			if svc.actor != tt.actor {
+				t.Errorf("GetItem() called with actor %+v, want %+v", svc.actor, tt.actor)
+			}
+			if tt.want == http.StatusOK && w.Header().Get("ETag") == "" {
+				t.Error("GET /api/items/1 returned no ETag")
+			}
+		})
+	}
+}
@@ -1,44 +1,267 @@
 // Package messages defines the request and response structures for the template plugin API
 package messages
 
-import "go.lumeweb.com/portal-plugin-template/internal/db/models"
+import (
+	"go.lumeweb.com/portal-plugin-template/internal/db/models"
+	"gorm.io/datatypes"
+	"time"
+)
 
 // ListItemsResponse represents the response for listing items
-// It includes pagination information and the items themselves
+// It includes pagination information and the items themselves.
+// Total and page are only set in page mode, the cursors only in cursor mode.
 type ListItemsResponse struct {
-	Items []models.Item `json:"items"`  // Array of items
-	Total int64        `json:"total"`   // Total number of items
-	Page  int          `json:"page"`    // Current page number
-	Limit int          `json:"limit"`   // Items per page
+	Items      []models.Item `json:"items"`                 // Array of items
+	Total      int64         `json:"total,omitempty"`       // Total number of items
+	Page       int           `json:"page,omitempty"`        // Current page number
+	Limit      int           `json:"limit"`                 // Items per page
+	NextCursor string        `json:"next_cursor,omitempty"` // Cursor for the next page
+	PrevCursor string        `json:"prev_cursor,omitempty"` // Cursor for the previous page
 }
 
 // CreateItemRequest represents the request body for creating a new item
 type CreateItemRequest struct {
-	Name        string `json:"name"`        // Required name field
-	Description string `json:"description"` // Optional description
+	Name        string         `json:"name" validate:"trim,required,max=name_length,chars=line"`      // Required name field
+	Description string         `json:"description" validate:"trim,max=description_length,chars=text"` // Optional description
+	Visibility  string         `json:"visibility" validate:"trim,oneof=public unlisted private"`      // Optional visibility, defaults to public
+	Metadata    datatypes.JSON `json:"metadata,omitempty"`                                            // Optional JSON object, checked against the metadata schema
 }
 
 // UpdateItemRequest represents the request body for updating an existing item
 type UpdateItemRequest struct {
-	Name        string `json:"name"`        // New name for the item
-	Description string `json:"description"` // New description
+	Name        string         `json:"name" validate:"trim,required,max=name_length,chars=line"`      // New name for the item
+	Description string         `json:"description" validate:"trim,max=description_length,chars=text"` // New description
+	Visibility  string         `json:"visibility" validate:"trim,oneof=public unlisted private"`      // New visibility, unchanged when empty
+	Metadata    datatypes.JSON `json:"metadata,omitempty"`                                            // New metadata, unchanged when absent and removed when null
+}
+
+// PatchItemRequest represents a JSON Merge Patch (RFC 7396) for an existing item
+// Absent fields are left unchanged. A null description clears it, a null visibility
+// resets it to public and a null name is rejected as the name is required. Metadata is
+// merged into the item's current metadata the same way, and a null removes it.
+type PatchItemRequest struct {
+	Name        Optional[string]         `json:"name" validate:"trim,required,max=name_length,chars=line"`      // New name for the item
+	Description Optional[string]         `json:"description" validate:"trim,max=description_length,chars=text"` // New description
+	Visibility  Optional[string]         `json:"visibility" validate:"trim,oneof=public unlisted private"`      // New visibility
+	Metadata    Optional[datatypes.JSON] `json:"metadata"`                                                      // Merge patch for the metadata
+}
+
+// BatchRequest represents a list of item operations to run in a single request
+// Operations run in one transaction and any failure rolls back the whole batch, unless
+// ContinueOnError is set, in which case every operation runs on its own and failures
+// are reported per operation.
+type BatchRequest struct {
+	ContinueOnError bool             `json:"continue_on_error"` // Run operations independently
+	Operations      []BatchOperation `json:"operations"`        // Operations to run, in order
+}
+
+// BatchOperation represents a single create, update or delete within a batch
+// Updates replace the item's values like PUT /api/items/{id} does.
+type BatchOperation struct {
+	Op          string         `json:"op" validate:"trim,required,oneof=create update delete"`        // Kind of operation
+	ID          uint64         `json:"id,omitempty"`                                                  // Item to update or delete
+	Version     uint           `json:"version,omitempty"`                                             // Expected item version, like If-Match
+	Name        string         `json:"name" validate:"trim,max=name_length,chars=line"`               // Name for creates and updates
+	Description string         `json:"description" validate:"trim,max=description_length,chars=text"` // Description for creates and updates
+	Visibility  string         `json:"visibility" validate:"trim,oneof=public unlisted private"`      // Visibility for creates and updates
+	Metadata    datatypes.JSON `json:"metadata,omitempty"`                                            // Metadata for creates and updates
+}
+
+// BatchOperationResult represents the outcome of a single batch operation
+type BatchOperationResult struct {
+	Index  int          `json:"index"`           // Position of the operation in the request
+	Op     string       `json:"op"`              // Kind of operation
+	Status int          `json:"status"`          // HTTP status the operation would have returned on its own
+	Item   *models.Item `json:"item,omitempty"`  // The created or updated item
+	Error  *ErrorDetail `json:"error,omitempty"` // Why the operation failed
+}
+
+// BatchResponse represents the response for a batch request
+type BatchResponse struct {
+	Results   []BatchOperationResult `json:"results"`   // One result per operation, in order
+	Succeeded int                    `json:"succeeded"` // Number of operations that succeeded
+	Failed    int                    `json:"failed"`    // Number of operations that failed
+}
+
+// SearchResult represents a single ranked search hit
+// The item fields are inlined alongside the relevance information
+type SearchResult struct {
+	models.Item
+	Score   float64 `json:"score"`   // Relevance score, higher is more relevant
+	Snippet string  `json:"snippet"` // Excerpt with matched terms wrapped in <mark> tags
 }
 
 // SearchItemsResponse represents the response for searching items
-// It includes the matching items and total count
+// It includes pagination information and the matching items ordered by relevance
 type SearchItemsResponse struct {
-	Items []models.Item `json:"items"`  // Array of matching items
-	Total int64        `json:"total"`   // Total number of matches
+	Items []SearchResult `json:"items"` // Array of matching items
+	Total int64          `json:"total"` // Total number of matches
+	Page  int            `json:"page"`  // Current page number
+	Limit int            `json:"limit"` // Results per page
+}
+
+// ListRevisionsResponse represents a page of an item's revision history, newest first
+type ListRevisionsResponse struct {
+	Revisions []models.ItemRevision `json:"revisions"` // Array of revisions
+	Total     int64                 `json:"total"`     // Total number of revisions
+	Page      int                   `json:"page"`      // Current page number
+	Limit     int                   `json:"limit"`     // Revisions per page
+}
+
+// FieldChange describes how a single item field changed in a revision
+type FieldChange struct {
+	Field string `json:"field"` // Name of the changed field
+	From  string `json:"from"`  // Value before the change
+	To    string `json:"to"`    // Value after the change
+}
+
+// RevisionResponse represents a single revision and the changes it made
+// The revision fields are inlined alongside the list of changes
+type RevisionResponse struct {
+	models.ItemRevision
+	Changes []FieldChange `json:"changes"` // Fields that differ from the previous revision
+}
+
+// TagRequest represents the request body for creating or updating a tag
+type TagRequest struct {
+	Name     string `json:"name" validate:"trim,required,max=64,chars=line"` // Tag name, stored in lowercase
+	Category string `json:"category" validate:"trim,max=64,chars=line"`      // Optional category to group the tag under
+}
+
+// SetItemTagsRequest represents the request body for replacing an item's tags
+// Tags that don't exist yet are created without a category.
+type SetItemTagsRequest struct {
+	Tags []string `json:"tags"` // Names of the tags to attach, an empty list removes all tags
+}
+
+// ListTagsResponse represents the response for listing tags
+type ListTagsResponse struct {
+	Tags []models.Tag `json:"tags"` // Array of tags ordered by category and name
+}
+
+// TagCount represents a tag and the number of matching items carrying it
+// The tag fields are inlined alongside the count
+type TagCount struct {
+	models.Tag
+	Count int64 `json:"count"` // Number of matching items carrying the tag
+}
+
+// TagCountsResponse represents the per-tag item counts used for faceted browsing
+type TagCountsResponse struct {
+	Tags []TagCount `json:"tags"` // Tags ordered from the most to the least used
+}
+
+// ImportResponse represents the response for starting an item import
+type ImportResponse struct {
+	ID string `json:"id"` // Identifier used to query the import's progress
+}
+
+// ImportRowError describes why a single row of an import was not imported
+type ImportRowError struct {
+	Row     int    `json:"row"`     // Position of the row in the import, starting at 1
+	Message string `json:"message"` // Why the row was rejected
+}
+
+// ImportState represents the progress of an item import
+// Only the first rejected rows are listed in Errors, Failed counts all of them.
+type ImportState struct {
+	ID        string           `json:"id"`              // Unique identifier for the import
+	Format    string           `json:"format"`          // Format of the imported data
+	Status    string           `json:"status"`          // State of the import workflow
+	Rows      int              `json:"rows"`            // Number of rows processed so far
+	Created   int              `json:"created"`         // Number of items created
+	Failed    int              `json:"failed"`          // Number of rows rejected
+	Errors    []ImportRowError `json:"errors"`          // Rejected rows and the reason for each
+	Error     string           `json:"error,omitempty"` // Why the import stopped early
+	Started   time.Time        `json:"started"`         // When the import started
+	Completed bool             `json:"completed"`       // Whether the import has finished
+}
+
+// ImportStatusResponse represents the response for checking import progress
+type ImportStatusResponse struct {
+	State *ImportState `json:"state"` // Current state of the import
+}
+
+// AttachmentRequest represents the query parameters describing an uploaded attachment
+type AttachmentRequest struct {
+	Name string `json:"name" validate:"trim,required,max=255,chars=line"` // File name, from the name parameter or Content-Disposition
+}
+
+// Attachment represents a file attached to an item along with the state of its upload workflow
+// The attachment fields are inlined alongside the status
+type Attachment struct {
+	models.ItemAttachment
+	Status    string `json:"status"`    // Status of the upload workflow, unknown if it cannot be read
+	Completed bool   `json:"completed"` // Whether the file has been stored
+}
+
+// ListAttachmentsResponse represents the response for listing an item's attachments
+type ListAttachmentsResponse struct {
+	Attachments []Attachment `json:"attachments"` // Attachments ordered from oldest to newest
+}
+
+// Error codes used in ErrorResponse
+const (
+	ErrorCodeBadRequest         = "bad_request"         // The request could not be parsed
+	ErrorCodeUnauthorized       = "unauthorized"        // No valid authentication was supplied
+	ErrorCodeForbidden          = "forbidden"           // The user may not perform the operation
+	ErrorCodeNotFound           = "not_found"           // The resource does not exist
+	ErrorCodeConflict           = "conflict"            // The change conflicts with the current state
+	ErrorCodePreconditionFailed = "precondition_failed" // The If-Match header does not match the current version
+	ErrorCodeValidationFailed   = "validation_failed"   // The request body failed validation
+	ErrorCodeQuotaExceeded      = "quota_exceeded"      // An item quota has been reached
+	ErrorCodePayloadTooLarge    = "payload_too_large"   // The request body exceeds the configured size limit
+	ErrorCodeInternal           = "internal_error"      // An unexpected server side failure
+)
+
+// ErrorResponse is the envelope returned by every failing API request
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"` // Details of the failure
+}
+
+// ErrorDetail describes an API error
+// Fields and Quota are only set for validation and quota errors respectively
+type ErrorDetail struct {
+	Code    string           `json:"code"`             // Machine readable error code
+	Message string           `json:"message"`          // Human readable error message
+	Fields  ValidationErrors `json:"fields,omitempty"` // Validation failures keyed by field name
+	Quota   *QuotaError      `json:"quota,omitempty"`  // The exceeded quota
+}
+
+// QuotaError describes the quota that was exceeded when creating an item
+type QuotaError struct {
+	Scope string `json:"scope"` // Which quota was exceeded: global or user
+	Usage int64  `json:"usage"` // Number of items counted against the quota
+	Limit int64  `json:"limit"` // The quota's limit
+}
+
+// QuotaResponse represents the current user's item usage and limits
+// A limit of zero means unlimited
+type QuotaResponse struct {
+	Usage       int64 `json:"usage"`        // Items owned by the user
+	Limit       int64 `json:"limit"`        // Per-user item limit
+	GlobalUsage int64 `json:"global_usage"` // Items stored across all users
+	GlobalLimit int64 `json:"global_limit"` // Portal wide item limit
 }
 
 // UploadState represents the current state of an upload operation
 type UploadState struct {
-	ID        string          `json:"id"`         // Unique identifier for the upload
-	Size      uint64         `json:"size"`       // Total size of the upload in bytes
-	Uploaded  uint64         `json:"uploaded"`   // Number of bytes uploaded so far
-	Started   time.Time      `json:"started"`    // When the upload started
-	Completed bool           `json:"completed"`  // Whether the upload is complete
-	Hash      string         `json:"hash"`       // Hash of the uploaded content
+	ID        string    `json:"id"`              // Unique identifier for the upload
+	Size      uint64    `json:"size"`            // Total size of the upload in bytes
+	Uploaded  uint64    `json:"uploaded"`        // Number of bytes uploaded so far
+	Phase     string    `json:"phase"`           // Current phase: receiving, hashing, storing, scanning, done or failed
+	Processed uint64    `json:"processed"`       // Number of bytes the current phase has processed
+	Error     string    `json:"error,omitempty"` // Why the upload failed, only set in the failed phase
+	Started   time.Time `json:"started"`         // When the upload started
+	Completed bool      `json:"completed"`       // Whether the upload is complete
+	Hash      string    `json:"hash"`            // Hash of the uploaded content
+}
+
+// UploadResponse represents the response for starting an upload
+type UploadResponse struct {
+	ID   string `json:"id"`   // Identifier used to query the upload's status
+	Hash string `json:"hash"` // Base58 multihash of the uploaded content
+	Size uint64 `json:"size"` // Size of the uploaded content in bytes
 }
 
 // UploadStatusResponse represents the response for checking upload status
@@ -0,0 +1,35 @@
+// Package messages implements optional fields for partial update requests
+package messages
+
+import "encoding/json"
+
+// Optional is a request field that distinguishes an absent key from an explicit
+// null, as needed for JSON Merge Patch (RFC 7396) semantics
+type Optional[T any] struct {
+	Set   bool // Whether the key was present in the request
+	Null  bool // Whether the key was present with a null value
+	Value T    // The decoded value when present and not null
+}
+
+// UnmarshalJSON is only invoked for keys present in the document, so any call marks the field as set
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+
+	if string(data) == "null" {
+		o.Null = true
+		var zero T
+		o.Value = zero
+		return nil
+	}
+
+	return json.Unmarshal(data, &o.Value)
+}
+
+// Ptr returns nil when the field was absent and a pointer to the value otherwise.
+// An explicit null yields a pointer to the zero value.
+func (o Optional[T]) Ptr() *T {
+	if !o.Set {
+		return nil
+	}
+	return &o.Value
+}
@@ -0,0 +1,160 @@
+package messages
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPatchItemRequestAbsentAndNull(t *testing.T) {
+	tests := []struct {
+		name            string
+		body            string
+		wantName        Optional[string]
+		wantDescription Optional[string]
+		wantVisibility  Optional[string]
+	}{
+		{
+			name: "empty patch leaves every field absent",
+			body: `{}`,
+		},
+		{
+			name:     "value sets the field",
+			body:     `{"name":"New name"}`,
+			wantName: Optional[string]{Set: true, Value: "New name"},
+		},
+		{
+			name:            "null is set and null",
+			body:            `{"description":null}`,
+			wantDescription: Optional[string]{Set: true, Null: true},
+		},
+		{
+			name:            "absent and null fields are distinguished in one patch",
+			body:            `{"description":null,"visibility":"private"}`,
+			wantDescription: Optional[string]{Set: true, Null: true},
+			wantVisibility:  Optional[string]{Set: true, Value: "private"},
+		},
+		{
+			name:            "empty string is set but not null",
+			body:            `{"description":""}`,
+			wantDescription: Optional[string]{Set: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var request PatchItemRequest
+			if err := json.Unmarshal([]byte(tt.body), &request); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+
+			if request.Name != tt.wantName {
+				t.Errorf("name = %+v, want %+v", request.Name, tt.wantName)
+			}
+			if request.Description != tt.wantDescription {
+				t.Errorf("description = %+v, want %+v", request.Description, tt.wantDescription)
+			}
+			if request.Visibility != tt.wantVisibility {
+				t.Errorf("visibility = %+v, want %+v", request.Visibility, tt.wantVisibility)
+			}
+		})
+	}
+}
+
+func TestOptionalPtr(t *testing.T) {
+	var absent Optional[string]
+	if absent.Ptr() != nil {
+		t.Errorf("absent field should map to nil")
+	}
+
+	null := Optional[string]{Set: true, Null: true}
+	if p := null.Ptr(); p == nil || *p != "" {
+		t.Errorf("null field should map to a pointer to the zero value, got %v", p)
+	}
+
+	value := Optional[string]{Set: true, Value: "x"}
+	if p := value.Ptr(); p == nil || *p != "x" {
+		t.Errorf("set field should map to a pointer to its value, got %v", p)
+	}
+}
+
+func TestPatchItemRequestMetadata(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantSet   bool
+		wantNull  bool
+		wantValue string
+	}{
+		{"absent metadata is not set", `{"name":"x"}`, false, false, ""},
+		{"null metadata removes it", `{"metadata":null}`, true, true, ""},
+		{"object is kept verbatim", `{"metadata":{"color":null,"size":3}}`, true, false, `{"color":null,"size":3}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var request PatchItemRequest
+			if err := json.Unmarshal([]byte(tt.body), &request); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+
+			got := request.Metadata
+			if got.Set != tt.wantSet || got.Null != tt.wantNull || string(got.Value) != tt.wantValue {
+				t.Errorf("metadata = {Set:%v Null:%v Value:%s}, want {Set:%v Null:%v Value:%s}",
+					got.Set, got.Null, got.Value, tt.wantSet, tt.wantNull, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestValidatePatchItemRequest(t *testing.T) {
+	limits := Limits{"name_length": 10, "description_length": 20}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantFields []string
+	}{
+		{"absent fields are not validated", `{}`, nil},
+		{"null name is rejected", `{"name":null}`, []string{"name"}},
+		{"blank name is rejected after trimming", `{"name":"   "}`, []string{"name"}},
+		{"null description is allowed", `{"description":null}`, nil},
+		{"null visibility is allowed", `{"visibility":null}`, nil},
+		{"unknown visibility is rejected", `{"visibility":"secret"}`, []string{"visibility"}},
+		{"long name is rejected", `{"name":"abcdefghijk"}`, []string{"name"}},
+		{"control characters in name are rejected", `{"name":"a\u0007b"}`, []string{"name"}},
+		{"newlines in description are allowed", `{"description":"a\nb"}`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var request PatchItemRequest
+			if err := json.Unmarshal([]byte(tt.body), &request); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+
+			errs := Validate(&request, limits)
+			if len(errs) != len(tt.wantFields) {
+				t.Fatalf("errors = %v, want failures for %v", errs, tt.wantFields)
+			}
+			for _, field := range tt.wantFields {
+				if _, ok := errs[field]; !ok {
+					t.Errorf("expected a failure for %q, got %v", field, errs)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateTrimsOptionalValues(t *testing.T) {
+	var request PatchItemRequest
+	if err := json.Unmarshal([]byte(`{"name":"  padded  "}`), &request); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if errs := Validate(&request, Limits{}); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if request.Name.Value != "padded" {
+		t.Errorf("name = %q, want it trimmed to %q", request.Name.Value, "padded")
+	}
+}
@@ -0,0 +1,148 @@
+// Package messages implements declarative validation of API request structures
+package messages
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits maps named limits used in validate tags to their configured values,
+// so that max=name_length resolves to Limits["name_length"]
+type Limits map[string]int
+
+// Character classes accepted by the chars rule
+const (
+	CharsLine = "line" // Printable characters on a single line
+	CharsText = "text" // Printable characters, newlines and tabs
+)
+
+// ValidationErrors maps JSON field names to a description of what is wrong with them
+type ValidationErrors map[string]string
+
+func (e ValidationErrors) Error() string {
+	return "validation failed"
+}
+
+// Validate checks the string fields of the struct pointed to by v against their
+// `validate` tags and returns the failures keyed by JSON field name.
+// Fields tagged with trim are trimmed in place before any other rule runs, so v
+// must be a pointer. Nil *string fields and absent Optional fields are skipped.
+//
+// Supported rules, separated by commas:
+//   - trim: strip leading and trailing whitespace
+//   - required: the value must not be empty
+//   - max=N or max=limit_name: at most N characters
+//   - chars=line or chars=text: restrict the allowed characters
+//   - oneof=a b c: the value must be empty or one of the listed values
+func Validate(v any, limits Limits) ValidationErrors {
+	errs := ValidationErrors{}
+
+	rv := reflect.ValueOf(v).Elem()
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag := field.Tag.Get("validate")
+		if tag == "" {
+			continue
+		}
+
+		value := rv.Field(i)
+		if value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				continue
+			}
+			value = value.Elem()
+		}
+		if value.Kind() == reflect.Struct && value.FieldByName("Set").IsValid() {
+			// Optional fields are skipped when absent, a null is validated as the zero value
+			if !value.FieldByName("Set").Bool() {
+				continue
+			}
+			value = value.FieldByName("Value")
+		}
+		if value.Kind() != reflect.String {
+			continue
+		}
+
+		name := jsonName(field)
+		if msg := validateField(value, tag, limits); msg != "" {
+			errs[name] = msg
+		}
+	}
+
+	return errs
+}
+
+// validateField applies the rules in tag to a single string field,
+// returning the first failure or an empty string
+func validateField(value reflect.Value, tag string, limits Limits) string {
+	rules := strings.Split(tag, ",")
+
+	for _, rule := range rules {
+		if rule == "trim" && value.CanSet() {
+			value.SetString(strings.TrimSpace(value.String()))
+		}
+	}
+
+	s := value.String()
+	for _, rule := range rules {
+		name, arg, _ := strings.Cut(rule, "=")
+
+		switch name {
+		case "required":
+			if s == "" {
+				return "is required"
+			}
+		case "max":
+			limit, err := strconv.Atoi(arg)
+			if err != nil {
+				limit = limits[arg]
+			}
+			if limit > 0 && utf8.RuneCountInString(s) > limit {
+				return fmt.Sprintf("must be at most %d characters", limit)
+			}
+		case "chars":
+			if !allowedChars(s, arg) {
+				return "contains characters that are not allowed"
+			}
+		case "oneof":
+			if s != "" && !slices.Contains(strings.Fields(arg), s) {
+				return fmt.Sprintf("must be one of: %s", strings.Join(strings.Fields(arg), ", "))
+			}
+		}
+	}
+
+	return ""
+}
+
+// allowedChars reports whether every character in s belongs to the given class
+func allowedChars(s string, class string) bool {
+	for _, r := range s {
+		if r == utf8.RuneError {
+			return false
+		}
+		if unicode.IsPrint(r) {
+			continue
+		}
+		if class == CharsText && (r == '\n' || r == '\r' || r == '\t') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// jsonName returns the name a struct field is encoded as in JSON
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
@@ -0,0 +1,78 @@
+// Package api implements the object download handler for the template plugin
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"go.lumeweb.com/portal-plugin-template/internal"
+	"go.lumeweb.com/portal-plugin-template/internal/protocol"
+	"go.lumeweb.com/portal/core"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// getObject handles GET /api/objects/{hash}
+// Streams a stored object by its base58 multihash. Range requests are supported and the
+// hash doubles as the ETag, as the content behind a hash never changes.
+// Objects are readable by their uploader and by anyone who can see an item they are attached to.
+func (a *API) getObject(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["hash"]
+
+	proto, ok := core.GetProtocol(internal.PLUGIN_NAME).(*protocol.Protocol)
+	if !ok {
+		a.writeError(w, errors.New("protocol not found"))
+		return
+	}
+
+	hash, err := proto.DecodeFileName(name)
+	if err != nil {
+		a.writeError(w, badRequest(err))
+		return
+	}
+	// Only serve the canonical name, so every object has exactly one URL and ETag
+	if proto.EncodeFileName(hash) != name {
+		a.writeError(w, badRequest(fmt.Errorf("%w: not in canonical form", protocol.ErrInvalidFileName)))
+		return
+	}
+
+	// The route is public, so the actor may be unknown
+	actor, _ := a.actorFromRequest(r)
+
+	obj, err := proto.OpenObject(r.Context(), hash)
+	if err != nil {
+		a.writeError(w, notFound(fmt.Errorf("object %s not found", name)))
+		return
+	}
+	defer func() {
+		if err := obj.Close(); err != nil {
+			a.logger.Error("failed to close object", zap.String("hash", name), zap.Error(err))
+		}
+	}()
+
+	allowed := actor != nil && obj.UploaderID() == actor.UserID
+	if !allowed {
+		allowed, err = a.itemSvc.CanReadObject(actor, name)
+		if err != nil {
+			a.writeError(w, err)
+			return
+		}
+	}
+	// Objects the actor may not read are reported as missing rather than forbidden
+	if !allowed {
+		a.writeError(w, notFound(fmt.Errorf("object %s not found", name)))
+		return
+	}
+
+	contentType := obj.MimeType()
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", fmt.Sprintf("%q", name))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	// ServeContent handles Range, If-Range, If-None-Match and Content-Length
+	http.ServeContent(w, r, "", obj.ModTime(), obj)
+}
@@ -0,0 +1,34 @@
+// Package api implements the quota handlers for the template plugin
+package api
+
+import (
+	"go.lumeweb.com/httputil"
+	"go.lumeweb.com/portal-plugin-template/internal/api/messages"
+	"net/http"
+)
+
+// getQuota handles GET /api/quota
+// Returns the authenticated user's item usage against the configured limits
+func (a *API) getQuota(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	actor, err := a.actorFromRequest(r)
+	if err != nil {
+		a.writeError(w, unauthorized(err))
+		return
+	}
+
+	quota, err := a.itemSvc.GetQuota(actor)
+	if err != nil {
+		a.writeError(w, err)
+		return
+	}
+
+	response := messages.QuotaResponse{
+		Usage:       quota.Usage,
+		Limit:       quota.Limit,
+		GlobalUsage: quota.GlobalUsage,
+		GlobalLimit: quota.GlobalLimit,
+	}
+	ctx.Encode(response)
+}
@@ -0,0 +1,55 @@
+// Package api implements response helpers shared by the API handlers
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"go.lumeweb.com/portal-plugin-template/internal"
+	"go.lumeweb.com/portal-plugin-template/internal/api/messages"
+	pluginConfig "go.lumeweb.com/portal-plugin-template/internal/config"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// writeJSON writes v as a JSON response body with the given status code
+func (a *API) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		a.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
+
+// decodeRequest decodes the JSON request body into v.
+// It writes a 400 error response and returns false when the body is malformed.
+func (a *API) decodeRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		a.writeError(w, badRequest(fmt.Errorf("invalid request body: %w", err)))
+		return false
+	}
+	return true
+}
+
+// validateRequest validates a decoded request body against the configured limits.
+// It writes a 400 response listing the failing fields and returns false when the
+// request is invalid.
+func (a *API) validateRequest(w http.ResponseWriter, request any) bool {
+	errs := messages.Validate(request, a.validationLimits())
+	if len(errs) == 0 {
+		return true
+	}
+
+	a.writeError(w, errs)
+	return false
+}
+
+// validationLimits returns the configured limits referenced by validate tags
+func (a *API) validationLimits() messages.Limits {
+	cfg := a.config.GetAPI(internal.PLUGIN_NAME).(*pluginConfig.APIConfig)
+
+	return messages.Limits{
+		"name_length":        cfg.MaxNameLength,
+		"description_length": cfg.MaxDescriptionLength,
+	}
+}
@@ -0,0 +1,129 @@
+// Package api implements the revision history handlers for the template plugin
+package api
+
+import (
+	"github.com/gorilla/mux"
+	"go.lumeweb.com/httputil"
+	"go.lumeweb.com/portal-plugin-template/internal/api/messages"
+	"net/http"
+	"strconv"
+)
+
+// revisionFromRequest reads the item ID and revision number from the route variables
+func revisionFromRequest(r *http.Request) (uint64, uint, error) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	rev, err := strconv.ParseUint(vars["rev"], 10, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return id, uint(rev), nil
+}
+
+// listRevisions handles GET /api/items/{id}/revisions
+// Returns a page of the item's revision history, newest first
+func (a *API) listRevisions(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	vars := mux.Vars(r)
+
+	id, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		a.writeError(w, badRequest(err))
+		return
+	}
+
+	// The route is public, so the actor may be unknown
+	actor, _ := a.actorFromRequest(r)
+
+	pagination := a.paginationFromRequest(r)
+
+	revisions, total, err := a.itemSvc.ListRevisions(actor, id, pagination)
+	if err != nil {
+		a.writeError(w, err)
+		return
+	}
+
+	response := messages.ListRevisionsResponse{
+		Revisions: revisions,
+		Total:     total,
+		Page:      pagination.Page,
+		Limit:     pagination.Limit,
+	}
+	ctx.Encode(response)
+}
+
+// getRevision handles GET /api/items/{id}/revisions/{rev}
+// Returns a single revision along with the fields it changed
+func (a *API) getRevision(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	id, rev, err := revisionFromRequest(r)
+	if err != nil {
+		a.writeError(w, badRequest(err))
+		return
+	}
+
+	// The route is public, so the actor may be unknown
+	actor, _ := a.actorFromRequest(r)
+
+	revision, err := a.itemSvc.GetRevision(actor, id, rev)
+	if err != nil {
+		a.writeError(w, err)
+		return
+	}
+
+	changes := make([]messages.FieldChange, 0, len(revision.Changes))
+	for _, change := range revision.Changes {
+		changes = append(changes, messages.FieldChange{
+			Field: change.Field,
+			From:  change.From,
+			To:    change.To,
+		})
+	}
+
+	response := messages.RevisionResponse{
+		ItemRevision: revision.ItemRevision,
+		Changes:      changes,
+	}
+	ctx.Encode(response)
+}
+
+// revertItem handles POST /api/items/{id}/revisions/{rev}/revert
+// Reverts the item to the given revision and returns the updated item.
+// An If-Match header makes the revert conditional on the item's current ETag.
+func (a *API) revertItem(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	id, rev, err := revisionFromRequest(r)
+	if err != nil {
+		a.writeError(w, badRequest(err))
+		return
+	}
+
+	actor, err := a.actorFromRequest(r)
+	if err != nil {
+		a.writeError(w, unauthorized(err))
+		return
+	}
+
+	version, err := versionFromRequest(r)
+	if err != nil {
+		a.writeError(w, err)
+		return
+	}
+
+	item, err := a.itemSvc.RevertItem(actor, id, rev, version)
+	if err != nil {
+		a.writeError(w, err)
+		return
+	}
+
+	setETag(w, item)
+	ctx.Encode(item)
+}
@@ -0,0 +1,210 @@
+// Package api implements the tag handlers for the template plugin
+package api
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"go.lumeweb.com/httputil"
+	"go.lumeweb.com/portal-plugin-template/internal"
+	"go.lumeweb.com/portal-plugin-template/internal/api/messages"
+	pluginConfig "go.lumeweb.com/portal-plugin-template/internal/config"
+	"net/http"
+	"strconv"
+)
+
+// validateItemTags checks the number of tags and validates each tag name.
+// Failures are keyed by the tag's position, such as tags[2].
+func (a *API) validateItemTags(request *messages.SetItemTagsRequest) messages.ValidationErrors {
+	errs := messages.ValidationErrors{}
+
+	maxTags := a.config.GetAPI(internal.PLUGIN_NAME).(*pluginConfig.APIConfig).MaxItemTags
+	if maxTags > 0 && len(request.Tags) > maxTags {
+		errs["tags"] = fmt.Sprintf("must contain at most %d tags", maxTags)
+		return errs
+	}
+
+	for i := range request.Tags {
+		tag := messages.TagRequest{Name: request.Tags[i]}
+		if msg, ok := messages.Validate(&tag, nil)["name"]; ok {
+			errs[fmt.Sprintf("tags[%d]", i)] = msg
+		}
+		request.Tags[i] = tag.Name
+	}
+
+	return errs
+}
+
+// listTags handles GET /api/tags
+// Returns every tag, or the tags in a single category when category is passed
+func (a *API) listTags(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	tags, err := a.itemSvc.ListTags(r.URL.Query().Get("category"))
+	if err != nil {
+		a.writeError(w, err)
+		return
+	}
+
+	ctx.Encode(messages.ListTagsResponse{Tags: tags})
+}
+
+// countTags handles GET /api/tags/counts
+// Returns the number of public items carrying each tag, narrowed by the same
+// filters as GET /api/items so the counts follow the current listing
+func (a *API) countTags(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	filter, err := filterFromRequest(r)
+	if err != nil {
+		a.writeError(w, badRequest(err))
+		return
+	}
+
+	counts, err := a.itemSvc.CountTags(nil, filter, r.URL.Query().Get("category"))
+	if err != nil {
+		a.writeError(w, err)
+		return
+	}
+
+	response := messages.TagCountsResponse{
+		Tags: make([]messages.TagCount, 0, len(counts)),
+	}
+	for _, count := range counts {
+		response.Tags = append(response.Tags, messages.TagCount{
+			Tag:   count.Tag,
+			Count: count.Count,
+		})
+	}
+
+	ctx.Encode(response)
+}
+
+// createTag handles POST /api/tags
+// Creates a new tag in a category and returns it with its location
+func (a *API) createTag(w http.ResponseWriter, r *http.Request) {
+	actor, err := a.actorFromRequest(r)
+	if err != nil {
+		a.writeError(w, unauthorized(err))
+		return
+	}
+
+	var request messages.TagRequest
+	if !a.decodeRequest(w, r, &request) {
+		return
+	}
+
+	if !a.validateRequest(w, &request) {
+		return
+	}
+
+	tag, err := a.itemSvc.CreateTag(actor, request.Name, request.Category)
+	if err != nil {
+		a.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/tags/%d", tag.ID))
+	a.writeJSON(w, http.StatusCreated, tag)
+}
+
+// updateTag handles PUT /api/tags/{id}
+// Renames a tag or moves it to another category and returns the updated tag
+func (a *API) updateTag(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		a.writeError(w, badRequest(err))
+		return
+	}
+
+	actor, err := a.actorFromRequest(r)
+	if err != nil {
+		a.writeError(w, unauthorized(err))
+		return
+	}
+
+	var request messages.TagRequest
+	if !a.decodeRequest(w, r, &request) {
+		return
+	}
+
+	if !a.validateRequest(w, &request) {
+		return
+	}
+
+	tag, err := a.itemSvc.UpdateTag(actor, id, request.Name, request.Category)
+	if err != nil {
+		a.writeError(w, err)
+		return
+	}
+
+	ctx.Encode(tag)
+}
+
+// deleteTag handles DELETE /api/tags/{id}
+// Deletes a tag and detaches it from every item
+func (a *API) deleteTag(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		a.writeError(w, badRequest(err))
+		return
+	}
+
+	actor, err := a.actorFromRequest(r)
+	if err != nil {
+		a.writeError(w, unauthorized(err))
+		return
+	}
+
+	if err := a.itemSvc.DeleteTag(actor, id); err != nil {
+		a.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// setItemTags handles PUT /api/items/{id}/tags
+// Replaces the tags attached to an item and returns the item with its new tags.
+// An If-Match header makes the change conditional on the item's current ETag.
+func (a *API) setItemTags(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		a.writeError(w, badRequest(err))
+		return
+	}
+
+	actor, err := a.actorFromRequest(r)
+	if err != nil {
+		a.writeError(w, unauthorized(err))
+		return
+	}
+
+	version, err := versionFromRequest(r)
+	if err != nil {
+		a.writeError(w, err)
+		return
+	}
+
+	var request messages.SetItemTagsRequest
+	if !a.decodeRequest(w, r, &request) {
+		return
+	}
+
+	if errs := a.validateItemTags(&request); len(errs) > 0 {
+		a.writeError(w, errs)
+		return
+	}
+
+	item, err := a.itemSvc.SetItemTags(actor, id, version, request.Tags)
+	if err != nil {
+		a.writeError(w, err)
+		return
+	}
+
+	setETag(w, item)
+	ctx.Encode(item)
+}
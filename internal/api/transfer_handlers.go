@@ -0,0 +1,213 @@
+// Package api implements the item import and export handlers for the template plugin
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"go.lumeweb.com/httputil"
+	"go.lumeweb.com/portal-plugin-template/internal"
+	"go.lumeweb.com/portal-plugin-template/internal/api/messages"
+	pluginConfig "go.lumeweb.com/portal-plugin-template/internal/config"
+	"go.lumeweb.com/portal-plugin-template/internal/db/models"
+	"go.lumeweb.com/portal-plugin-template/internal/protocol"
+	"go.lumeweb.com/portal-plugin-template/internal/service"
+	"go.lumeweb.com/portal/core"
+	"go.uber.org/zap"
+	"mime"
+	"net/http"
+)
+
+// transferContentTypes maps transfer formats to their media types
+var transferContentTypes = map[string]string{
+	service.FormatCSV:    "text/csv",
+	service.FormatNDJSON: "application/x-ndjson",
+}
+
+// payloadTooLarge marks err as a request body exceeding its size limit
+func payloadTooLarge(err error) error {
+	return &statusError{http.StatusRequestEntityTooLarge, messages.ErrorCodePayloadTooLarge, err}
+}
+
+// transferFormatFromRequest reads the transfer format from the format query parameter,
+// falling back to the format matching the request's Content-Type.
+func transferFormatFromRequest(r *http.Request) (string, error) {
+	format := r.URL.Query().Get("format")
+
+	if format == "" {
+		if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil {
+			for f, contentType := range transferContentTypes {
+				if contentType == mediaType {
+					format = f
+				}
+			}
+		}
+	}
+
+	if !service.ValidTransferFormat(format) {
+		return "", fmt.Errorf("unsupported format %q", format)
+	}
+
+	return format, nil
+}
+
+// exportItems handles GET /api/items/export
+// Streams the authenticated user's items matching the filter as CSV or NDJSON.
+// Items are written as they are read, so the export is never held in memory.
+func (a *API) exportItems(w http.ResponseWriter, r *http.Request) {
+	actor, err := a.actorFromRequest(r)
+	if err != nil {
+		a.writeError(w, unauthorized(err))
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = service.FormatNDJSON
+	}
+	if !service.ValidTransferFormat(format) {
+		a.writeError(w, badRequest(fmt.Errorf("unsupported format %q", format)))
+		return
+	}
+
+	filter, err := filterFromRequest(r)
+	if err != nil {
+		a.writeError(w, badRequest(err))
+		return
+	}
+
+	writer, err := service.NewItemWriter(format, w)
+	if err != nil {
+		a.writeError(w, err)
+		return
+	}
+
+	// Headers are only sent once the first item is ready, so a failing
+	// query can still be reported as an error response
+	started := false
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+
+		w.Header().Set("Content-Type", transferContentTypes[format])
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="items.%s"`, format))
+		w.WriteHeader(http.StatusOK)
+	}
+
+	err = a.itemSvc.ExportItems(actor, filter, func(item *models.Item) error {
+		start()
+		return writer.Write(item)
+	})
+	if err != nil && !started {
+		a.writeError(w, err)
+		return
+	}
+	if err != nil {
+		// The response is already underway, the client sees a truncated export
+		a.logger.Error("failed to export items", zap.Error(err))
+		return
+	}
+
+	start()
+	if err := writer.Flush(); err != nil {
+		a.logger.Error("failed to flush export", zap.Error(err))
+	}
+}
+
+// importItems handles POST /api/items/import
+// Stages a CSV or NDJSON body and imports it in the background through the import workflow.
+// Rows are validated one by one, the returned import ID reports the outcome of each.
+func (a *API) importItems(w http.ResponseWriter, r *http.Request) {
+	actor, err := a.actorFromRequest(r)
+	if err != nil {
+		a.writeError(w, unauthorized(err))
+		return
+	}
+
+	format, err := transferFormatFromRequest(r)
+	if err != nil {
+		a.writeError(w, badRequest(err))
+		return
+	}
+
+	if maxSize := a.config.GetAPI(internal.PLUGIN_NAME).(*pluginConfig.APIConfig).MaxImportSize; maxSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+	}
+
+	proto, ok := core.GetProtocol(internal.PLUGIN_NAME).(*protocol.Protocol)
+	if !ok {
+		a.writeError(w, errors.New("protocol not found"))
+		return
+	}
+
+	importID, err := proto.StartImport(r.Context(), actor.UserID, format, r.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			err = payloadTooLarge(fmt.Errorf("import exceeds %d bytes", maxBytesErr.Limit))
+		}
+		a.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/imports/%s", importID))
+	a.writeJSON(w, http.StatusAccepted, messages.ImportResponse{ID: importID})
+}
+
+// getImportStatus handles GET /api/imports/{id}
+// Returns the progress of an import started by the authenticated user
+func (a *API) getImportStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	importID := mux.Vars(r)["id"]
+
+	actor, err := a.actorFromRequest(r)
+	if err != nil {
+		a.writeError(w, unauthorized(err))
+		return
+	}
+
+	proto, ok := core.GetProtocol(internal.PLUGIN_NAME).(*protocol.Protocol)
+	if !ok {
+		a.writeError(w, errors.New("protocol not found"))
+		return
+	}
+
+	state, err := proto.GetImportStatus(importID)
+	if err != nil {
+		a.writeError(w, notFound(err))
+		return
+	}
+
+	// Imports of other users are reported as missing rather than forbidden
+	if !actor.Admin && state.UserID != actor.UserID {
+		a.writeError(w, notFound(fmt.Errorf("import %s not found", importID)))
+		return
+	}
+
+	rowErrors := make([]messages.ImportRowError, 0, len(state.Errors))
+	for _, rowErr := range state.Errors {
+		rowErrors = append(rowErrors, messages.ImportRowError{
+			Row:     rowErr.Row,
+			Message: rowErr.Message,
+		})
+	}
+
+	response := messages.ImportStatusResponse{
+		State: &messages.ImportState{
+			ID:        state.ID,
+			Format:    state.Format,
+			Status:    state.Status,
+			Rows:      state.Rows,
+			Created:   state.Created,
+			Failed:    state.Failed,
+			Errors:    rowErrors,
+			Error:     state.Error,
+			Started:   state.Started,
+			Completed: state.Completed,
+		},
+	}
+
+	ctx.Encode(response)
+}
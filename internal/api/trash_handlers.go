@@ -0,0 +1,72 @@
+// Package api implements the trash handlers for the template plugin
+package api
+
+import (
+	"github.com/gorilla/mux"
+	"go.lumeweb.com/httputil"
+	"net/http"
+	"strconv"
+)
+
+// listTrash handles GET /api/items/trash
+// Returns a sorted and paginated list of the authenticated user's deleted items
+func (a *API) listTrash(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+
+	actor, err := a.actorFromRequest(r)
+	if err != nil {
+		a.writeError(w, unauthorized(err))
+		return
+	}
+
+	pagination := a.paginationFromRequest(r)
+
+	filter, err := filterFromRequest(r)
+	if err != nil {
+		a.writeError(w, badRequest(err))
+		return
+	}
+
+	items, total, err := a.itemSvc.ListTrash(actor, filter, pagination)
+	if err != nil {
+		a.writeError(w, err)
+		return
+	}
+
+	ctx.Encode(listItemsResponse(items, total, pagination))
+}
+
+// restoreItem handles POST /api/items/{id}/restore
+// Moves a deleted item out of the trash and returns the restored item.
+// An If-Match header makes the restore conditional on the deleted item's ETag.
+func (a *API) restoreItem(w http.ResponseWriter, r *http.Request) {
+	ctx := httputil.Context(r, w)
+	vars := mux.Vars(r)
+
+	id, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		a.writeError(w, badRequest(err))
+		return
+	}
+
+	actor, err := a.actorFromRequest(r)
+	if err != nil {
+		a.writeError(w, unauthorized(err))
+		return
+	}
+
+	version, err := versionFromRequest(r)
+	if err != nil {
+		a.writeError(w, err)
+		return
+	}
+
+	item, err := a.itemSvc.RestoreItem(actor, id, version)
+	if err != nil {
+		a.writeError(w, err)
+		return
+	}
+
+	setETag(w, item)
+	ctx.Encode(item)
+}
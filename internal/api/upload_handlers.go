@@ -0,0 +1,367 @@
+// Package api implements the file upload handlers for the template plugin
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	tusd "github.com/tus/tusd/v2/pkg/handler"
+	"go.lumeweb.com/portal-plugin-template/internal"
+	"go.lumeweb.com/portal-plugin-template/internal/api/messages"
+	pluginConfig "go.lumeweb.com/portal-plugin-template/internal/config"
+	"go.lumeweb.com/portal-plugin-template/internal/protocol"
+	"go.lumeweb.com/portal-plugin-template/internal/service"
+	"go.lumeweb.com/portal/core"
+	"go.uber.org/zap"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// uploadFormField is the multipart form field carrying the uploaded file
+	uploadFormField = "file"
+
+	// uploadSizeField is the multipart form field declaring the size of the uploaded file.
+	// Declaring it before the file field lets the file be streamed as it arrives.
+	uploadSizeField = "size"
+
+	// uploadEventsKeepAlive is how often an idle upload event stream sends a comment,
+	// so proxies don't close it while a workflow step runs without progress
+	uploadEventsKeepAlive = 15 * time.Second
+)
+
+// uploadTooLarge reports an upload exceeding the configured size limit
+func uploadTooLarge(limit int64) error {
+	return payloadTooLarge(fmt.Errorf("upload exceeds %d bytes", limit))
+}
+
+// uploadBodyFromRequest returns the file carried by an upload request and its size,
+// enforcing the configured max upload size
+func (a *API) uploadBodyFromRequest(w http.ResponseWriter, r *http.Request) (io.ReadCloser, uint64, error) {
+	cfg := a.config.GetProtocol(internal.PLUGIN_NAME).(*pluginConfig.Config)
+	return uploadBody(w, r, cfg.MaxUploadSize, filepath.Join(cfg.StoragePath, "spool"))
+}
+
+// uploadBody returns the file carried by an upload request and its size.
+// multipart/form-data requests carry the file in the file field, other requests carry it as
+// the raw body and must declare its size with Content-Length. Request bodies above maxSize
+// are rejected with 413, zero means no limit.
+// The file is streamed from the request body when its size is known up front, from a size
+// field preceding the file field or the Content-Length of the file part. Otherwise it is
+// spooled to a file in spoolDir, which is removed once the returned body is closed.
+func uploadBody(w http.ResponseWriter, r *http.Request, maxSize int64, spoolDir string) (io.ReadCloser, uint64, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		if r.ContentLength < 0 {
+			return nil, 0, badRequest(errors.New("missing Content-Length header"))
+		}
+		if maxSize > 0 && r.ContentLength > maxSize {
+			return nil, 0, uploadTooLarge(maxSize)
+		}
+		return r.Body, uint64(r.ContentLength), nil
+	}
+
+	if maxSize > 0 {
+		if r.ContentLength > maxSize {
+			return nil, 0, uploadTooLarge(maxSize)
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, 0, badRequest(err)
+	}
+
+	size := int64(-1)
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, 0, badRequest(fmt.Errorf("missing %s field", uploadFormField))
+		}
+		if err != nil {
+			return nil, 0, uploadError(err, maxSize)
+		}
+
+		switch part.FormName() {
+		case uploadSizeField:
+			value, err := io.ReadAll(io.LimitReader(part, 32))
+			if err != nil {
+				return nil, 0, uploadError(err, maxSize)
+			}
+			size, err = strconv.ParseInt(strings.TrimSpace(string(value)), 10, 64)
+			if err != nil || size < 0 {
+				return nil, 0, badRequest(fmt.Errorf("invalid %s field %q", uploadSizeField, value))
+			}
+			if maxSize > 0 && size > maxSize {
+				return nil, 0, uploadTooLarge(maxSize)
+			}
+		case uploadFormField:
+			if size < 0 {
+				if length := part.Header.Get("Content-Length"); length != "" {
+					size, err = strconv.ParseInt(length, 10, 64)
+					if err != nil || size < 0 {
+						_ = part.Close()
+						return nil, 0, badRequest(fmt.Errorf("invalid Content-Length %q of the %s field", length, uploadFormField))
+					}
+				}
+			}
+			if maxSize > 0 && size > maxSize {
+				_ = part.Close()
+				return nil, 0, uploadTooLarge(maxSize)
+			}
+			if size < 0 {
+				return spoolUpload(part, maxSize, spoolDir)
+			}
+			return part, uint64(size), nil
+		}
+	}
+}
+
+// uploadError maps an error reading a multipart upload body, reporting bodies above
+// maxSize as too large
+func uploadError(err error, maxSize int64) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return uploadTooLarge(maxSize)
+	}
+	return badRequest(err)
+}
+
+// spooledUpload is an uploaded file spooled to disk, removed once it is closed
+type spooledUpload struct {
+	*os.File
+}
+
+func (f spooledUpload) Close() error {
+	err := f.File.Close()
+	_ = os.Remove(f.Name())
+	return err
+}
+
+// spoolUpload copies a file of unknown size to a file in dir, so its size is known before
+// it is stored. Returns the spooled file positioned at its start and its size.
+func spoolUpload(part io.ReadCloser, maxSize int64, dir string) (io.ReadCloser, uint64, error) {
+	defer part.Close()
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, 0, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+	file, err := os.CreateTemp(dir, "upload-*.tmp")
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create upload file: %w", err)
+	}
+	spooled := spooledUpload{file}
+
+	size, err := io.Copy(file, part)
+	if err != nil {
+		_ = spooled.Close()
+		return nil, 0, uploadError(err, maxSize)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		_ = spooled.Close()
+		return nil, 0, fmt.Errorf("failed to spool upload: %w", err)
+	}
+
+	return spooled, uint64(size), nil
+}
+
+// uploadFile handles POST /api/uploads
+// Streams a raw or multipart body through the upload workflow and returns the upload ID,
+// which GET /api/uploads/{id} reports the progress of, along with the content hash.
+func (a *API) uploadFile(w http.ResponseWriter, r *http.Request) {
+	actor, err := a.actorFromRequest(r)
+	if err != nil {
+		a.writeError(w, unauthorized(err))
+		return
+	}
+
+	proto, ok := core.GetProtocol(internal.PLUGIN_NAME).(*protocol.Protocol)
+	if !ok {
+		a.writeError(w, errors.New("protocol not found"))
+		return
+	}
+
+	body, size, err := a.uploadBodyFromRequest(w, r)
+	if err != nil {
+		a.writeError(w, err)
+		return
+	}
+	defer body.Close()
+
+	state, err := proto.Upload(r.Context(), actor.UserID, body, size)
+	if err != nil {
+		a.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/uploads/%s", state.ID))
+	a.writeJSON(w, http.StatusAccepted, messages.UploadResponse{
+		ID:   state.ID,
+		Hash: proto.EncodeFileName(state.Hash),
+		Size: state.Size,
+	})
+}
+
+// tusUpload wraps a tus handler method so it runs on behalf of the authenticated user
+// The tus endpoints implement resumable uploads, completed uploads start the upload workflow
+// and their tus ID can be passed to GET /api/uploads/{id} while they are still receiving data.
+// Existing uploads can only be resumed, inspected or cancelled by their creator and by admins.
+func (a *API) tusUpload(handle func(*tusd.UnroutedHandler, http.ResponseWriter, *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		actor, err := a.actorFromRequest(r)
+		if err != nil {
+			a.writeError(w, unauthorized(err))
+			return
+		}
+
+		proto, ok := core.GetProtocol(internal.PLUGIN_NAME).(*protocol.Protocol)
+		if !ok {
+			a.writeError(w, errors.New("protocol not found"))
+			return
+		}
+
+		// Uploads of other users are reported as missing rather than forbidden
+		if id := mux.Vars(r)["id"]; id != "" {
+			owner, err := proto.TusUploadOwner(r.Context(), id)
+			if err != nil || (!actor.Admin && owner != actor.UserID) {
+				a.writeError(w, notFound(fmt.Errorf("upload %s not found", id)))
+				return
+			}
+		}
+
+		// tusd reads the upload ID from the path below its base path
+		tus := proto.TusHandler()
+		http.StripPrefix(protocol.TusBasePath, tus.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handle(tus, w, r)
+		}))).ServeHTTP(w, r.WithContext(protocol.WithUploader(r.Context(), actor.UserID)))
+	}
+}
+
+// getUploadEvents handles GET /api/uploads/{id}/events
+// Streams the progress of an upload as Server-Sent Events. A phase event is sent whenever
+// the upload moves to the next phase, such as the store and scan steps of the upload
+// workflow, and a progress event whenever its byte counters change. The stream closes
+// once the upload is done or failed.
+func (a *API) getUploadEvents(w http.ResponseWriter, r *http.Request) {
+	uploadID := mux.Vars(r)["id"]
+
+	actor, err := a.actorFromRequest(r)
+	if err != nil {
+		a.writeError(w, unauthorized(err))
+		return
+	}
+
+	proto, ok := core.GetProtocol(internal.PLUGIN_NAME).(*protocol.Protocol)
+	if !ok {
+		a.writeError(w, errors.New("protocol not found"))
+		return
+	}
+
+	updates, err := proto.WatchUpload(r.Context(), uploadID)
+	if err != nil {
+		a.writeError(w, notFound(err))
+		return
+	}
+
+	a.streamUploadEvents(w, actor, proto, uploadID, updates)
+}
+
+// streamUploadEvents writes the upload states received from updates as Server-Sent Events
+// until updates is closed. The first state is the upload's current status, which decides
+// whether the actor may follow it.
+func (a *API) streamUploadEvents(w http.ResponseWriter, actor *service.Actor, proto *protocol.Protocol, uploadID string, updates <-chan *protocol.UploadState) {
+	state, ok := <-updates
+	if !ok {
+		return
+	}
+
+	// Uploads of other users are reported as missing rather than forbidden
+	if !actor.Admin && state.UserID != actor.UserID {
+		a.writeError(w, notFound(fmt.Errorf("upload %s not found", uploadID)))
+		return
+	}
+
+	// The stream outlives any write timeout meant for regular responses
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// sendState writes the current state as an event, a phase event if it moved to the next phase
+	phase := ""
+	sendState := func() error {
+		event := "progress"
+		if state.Phase != phase {
+			event = "phase"
+			phase = state.Phase
+		}
+
+		message := &messages.UploadState{
+			ID:        uploadID,
+			Size:      state.Size,
+			Uploaded:  state.Uploaded,
+			Phase:     state.Phase,
+			Processed: state.Processed,
+			Error:     state.Error,
+			Started:   state.Started,
+			Completed: state.Completed,
+		}
+		if state.Hash != nil {
+			message.Hash = proto.EncodeFileName(state.Hash)
+		}
+
+		if err := writeEvent(w, event, message); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	if err := sendState(); err != nil {
+		a.logger.Error("failed to send upload event", zap.String("upload_id", uploadID), zap.Error(err))
+		return
+	}
+
+	keepAlive := time.NewTicker(uploadEventsKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case state, ok = <-updates:
+			if !ok {
+				return
+			}
+			if err := sendState(); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeEvent writes a Server-Sent Event carrying v as JSON data
+func writeEvent(w io.Writer, event string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
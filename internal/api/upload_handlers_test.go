@@ -0,0 +1,336 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go.lumeweb.com/portal-plugin-template/internal/api/messages"
+	"go.lumeweb.com/portal-plugin-template/internal/protocol"
+	"go.lumeweb.com/portal-plugin-template/internal/protocol/handlers"
+	"go.lumeweb.com/portal-plugin-template/internal/service"
+)
+
+// multipartUpload builds a multipart body from form fields in order
+func multipartUpload(t *testing.T, fields ...[2]string) (*bytes.Buffer, string) {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for _, field := range fields {
+		var (
+			part io.Writer
+			err  error
+		)
+		if field[0] == uploadFormField {
+			part, err = form.CreateFormFile(field[0], "data.bin")
+		} else {
+			part, err = form.CreateFormField(field[0])
+		}
+		if err != nil {
+			t.Fatalf("failed to create %s field: %v", field[0], err)
+		}
+		_, _ = io.WriteString(part, field[1])
+	}
+	if err := form.Close(); err != nil {
+		t.Fatalf("failed to close multipart body: %v", err)
+	}
+
+	return &body, form.FormDataContentType()
+}
+
+func TestUploadBody(t *testing.T) {
+	// Room for the multipart framing of the small files below
+	const limit = 512
+
+	a := &API{}
+	spool := t.TempDir()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, size, err := uploadBody(w, r, limit, spool)
+		if err != nil {
+			a.writeError(w, err)
+			return
+		}
+		defer body.Close()
+
+		data, err := io.ReadAll(body)
+		if err != nil {
+			a.writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = fmt.Fprintf(w, "%d:%s", size, data)
+	})
+
+	tests := []struct {
+		name     string
+		request  func() *http.Request
+		want     int
+		wantBody string
+	}{
+		{
+			name: "raw body",
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/api/uploads", strings.NewReader("raw data"))
+			},
+			want:     http.StatusAccepted,
+			wantBody: "8:raw data",
+		},
+		{
+			name: "raw body without Content-Length",
+			request: func() *http.Request {
+				r := httptest.NewRequest(http.MethodPost, "/api/uploads", strings.NewReader("raw data"))
+				r.ContentLength = -1
+				return r
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "raw body above the limit",
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/api/uploads", strings.NewReader(strings.Repeat("x", limit+1)))
+			},
+			want: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "multipart file",
+			request: func() *http.Request {
+				body, contentType := multipartUpload(t, [2]string{uploadSizeField, "9"}, [2]string{uploadFormField, "form data"})
+				r := httptest.NewRequest(http.MethodPost, "/api/uploads", body)
+				r.Header.Set("Content-Type", contentType)
+				return r
+			},
+			want:     http.StatusAccepted,
+			wantBody: "9:form data",
+		},
+		{
+			name: "multipart size above the limit",
+			request: func() *http.Request {
+				body, contentType := multipartUpload(t, [2]string{uploadSizeField, strconv.Itoa(limit + 1)}, [2]string{uploadFormField, "x"})
+				r := httptest.NewRequest(http.MethodPost, "/api/uploads", body)
+				r.Header.Set("Content-Type", contentType)
+				return r
+			},
+			want: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "multipart body above the limit",
+			request: func() *http.Request {
+				body, contentType := multipartUpload(t, [2]string{"note", strings.Repeat("x", limit)}, [2]string{uploadSizeField, "1"}, [2]string{uploadFormField, "x"})
+				r := httptest.NewRequest(http.MethodPost, "/api/uploads", body)
+				r.Header.Set("Content-Type", contentType)
+				return r
+			},
+			want: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "multipart file without size",
+			request: func() *http.Request {
+				body, contentType := multipartUpload(t, [2]string{uploadFormField, "form data"}, [2]string{uploadSizeField, "9"})
+				r := httptest.NewRequest(http.MethodPost, "/api/uploads", body)
+				r.Header.Set("Content-Type", contentType)
+				return r
+			},
+			want:     http.StatusAccepted,
+			wantBody: "9:form data",
+		},
+		{
+			name: "multipart file without size above the limit",
+			request: func() *http.Request {
+				body, contentType := multipartUpload(t, [2]string{uploadFormField, strings.Repeat("x", limit)})
+				r := httptest.NewRequest(http.MethodPost, "/api/uploads", body)
+				r.Header.Set("Content-Type", contentType)
+				r.ContentLength = -1
+				return r
+			},
+			want: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "multipart file with Content-Length",
+			request: func() *http.Request {
+				var body bytes.Buffer
+				form := multipart.NewWriter(&body)
+				part, err := form.CreatePart(textproto.MIMEHeader{
+					"Content-Disposition": {`form-data; name="file"; filename="data.bin"`},
+					"Content-Length":      {"9"},
+				})
+				if err != nil {
+					t.Fatalf("failed to create file field: %v", err)
+				}
+				_, _ = io.WriteString(part, "form data")
+				_ = form.Close()
+
+				r := httptest.NewRequest(http.MethodPost, "/api/uploads", &body)
+				r.Header.Set("Content-Type", form.FormDataContentType())
+				return r
+			},
+			want:     http.StatusAccepted,
+			wantBody: "9:form data",
+		},
+		{
+			name: "multipart without file",
+			request: func() *http.Request {
+				body, contentType := multipartUpload(t, [2]string{uploadSizeField, "9"})
+				r := httptest.NewRequest(http.MethodPost, "/api/uploads", body)
+				r.Header.Set("Content-Type", contentType)
+				return r
+			},
+			want: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, tt.request())
+
+			if w.Code != tt.want {
+				t.Fatalf("POST /api/uploads status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("POST /api/uploads read %q, want %q", w.Body, tt.wantBody)
+			}
+			if spooled, _ := os.ReadDir(spool); len(spooled) != 0 {
+				t.Errorf("spooled upload files left behind: %v", spooled)
+			}
+		})
+	}
+}
+
+// uploadUpdates returns a closed channel holding the given upload states
+func uploadUpdates(states ...*protocol.UploadState) <-chan *protocol.UploadState {
+	updates := make(chan *protocol.UploadState, len(states))
+	for _, state := range states {
+		updates <- state
+	}
+	close(updates)
+	return updates
+}
+
+// uploadEvent is a Server-Sent Event read back from a response
+type uploadEvent struct {
+	name  string
+	state messages.UploadState
+}
+
+// readUploadEvents parses the events written to an upload event stream
+func readUploadEvents(t *testing.T, body string) []uploadEvent {
+	t.Helper()
+
+	var events []uploadEvent
+	for _, block := range strings.Split(strings.TrimSpace(body), "\n\n") {
+		var event uploadEvent
+		for _, line := range strings.Split(block, "\n") {
+			field, value, _ := strings.Cut(line, ": ")
+			switch field {
+			case "event":
+				event.name = value
+			case "data":
+				if err := json.Unmarshal([]byte(value), &event.state); err != nil {
+					t.Fatalf("invalid event data %q: %v", value, err)
+				}
+			}
+		}
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func TestStreamUploadEvents(t *testing.T) {
+	a := &API{}
+	owner := &service.Actor{UserID: 7}
+
+	tests := []struct {
+		name   string
+		states []*protocol.UploadState
+		want   []string
+		last   string
+	}{
+		{
+			name: "done",
+			states: []*protocol.UploadState{
+				{UserID: 7, Size: 100, Uploaded: 100, Phase: handlers.UploadPhaseStoring},
+				{UserID: 7, Size: 100, Uploaded: 100, Phase: handlers.UploadPhaseStoring, Processed: 50},
+				{UserID: 7, Size: 100, Uploaded: 100, Phase: handlers.UploadPhaseScanning},
+				{UserID: 7, Size: 100, Uploaded: 100, Phase: handlers.UploadPhaseDone, Completed: true},
+			},
+			want: []string{"phase", "progress", "phase", "phase"},
+			last: handlers.UploadPhaseDone,
+		},
+		{
+			name: "failed",
+			states: []*protocol.UploadState{
+				{UserID: 7, Size: 100, Uploaded: 100, Phase: handlers.UploadPhaseStoring},
+				{UserID: 7, Size: 100, Uploaded: 100, Phase: handlers.UploadPhaseFailed, Error: "storage unavailable"},
+			},
+			want: []string{"phase", "phase"},
+			last: handlers.UploadPhaseFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			a.streamUploadEvents(w, owner, &protocol.Protocol{}, "42", uploadUpdates(tt.states...))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET /api/uploads/42/events status = %d, want 200: %s", w.Code, w.Body)
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != "text/event-stream" {
+				t.Errorf("Content-Type = %q, want text/event-stream", contentType)
+			}
+
+			events := readUploadEvents(t, w.Body.String())
+			if len(events) != len(tt.want) {
+				t.Fatalf("got %d events, want %d: %s", len(events), len(tt.want), w.Body)
+			}
+			for n, event := range events {
+				if event.name != tt.want[n] {
+					t.Errorf("event %d = %q, want %q", n, event.name, tt.want[n])
+				}
+				if event.state.ID != "42" {
+					t.Errorf("event %d reports upload %q, want 42", n, event.state.ID)
+				}
+			}
+
+			last := events[len(events)-1].state
+			if last.Phase != tt.last {
+				t.Errorf("last event phase = %q, want %q", last.Phase, tt.last)
+			}
+			if last.Phase == handlers.UploadPhaseFailed && last.Error == "" {
+				t.Error("failed event carries no error")
+			}
+		})
+	}
+}
+
+func TestStreamUploadEventsOtherUser(t *testing.T) {
+	a := &API{}
+	state := &protocol.UploadState{UserID: 8, Size: 100, Phase: handlers.UploadPhaseStoring}
+
+	w := httptest.NewRecorder()
+	a.streamUploadEvents(w, &service.Actor{UserID: 7}, &protocol.Protocol{}, "42", uploadUpdates(state))
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("GET /api/uploads/42/events status = %d, want 404: %s", w.Code, w.Body)
+	}
+	if strings.Contains(w.Body.String(), "event:") {
+		t.Errorf("events of another user's upload were streamed: %s", w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	a.streamUploadEvents(w, &service.Actor{UserID: 1, Admin: true}, &protocol.Protocol{}, "42", uploadUpdates(state))
+	if w.Code != http.StatusOK {
+		t.Errorf("GET /api/uploads/42/events as admin status = %d, want 200", w.Code)
+	}
+}
@@ -13,36 +13,66 @@ var _ config.APIConfig = (*APIConfig)(nil)
 
 // Config defines all configuration options for the template plugin
 type Config struct {
-	StoragePath  string `config:"storage_path"`  // Path to store protocol data
-	MaxItems     int    `config:"max_items"`     // Maximum number of items to store
-	CacheEnabled bool   `config:"cache_enabled"` // Whether to enable caching
-	API          APIConfig `config:"api"`        // API-specific configuration
+	StoragePath        string    `config:"storage_path"`         // Path to store protocol data
+	MaxItems           int       `config:"max_items"`            // Maximum number of items to store, 0 for unlimited
+	CacheEnabled       bool      `config:"cache_enabled"`        // Whether to enable caching
+	TrashRetentionDays int       `config:"trash_retention_days"` // Days deleted items are kept before being purged, 0 to keep them forever
+	MaxUploadSize      int64     `config:"max_upload_size"`      // Maximum size of an uploaded file in bytes, 0 for unlimited
+	StoreMemoryLimit   int64     `config:"store_memory_limit"`   // Maximum size of an upload buffered in memory while it is stored, larger ones are spooled to disk
+	API                APIConfig `config:"api"`                  // API-specific configuration
 }
 
 // APIConfig defines the API-specific configuration options
 type APIConfig struct {
-	ItemsPerPage int `config:"items_per_page"` // Number of items to return per page
-	SearchLimit  int `config:"search_limit"`   // Maximum number of search results
+	ItemsPerPage         int    `config:"items_per_page"`         // Number of items to return per page
+	SearchLimit          int    `config:"search_limit"`           // Maximum number of search results per page
+	UserMaxItems         int    `config:"user_max_items"`         // Maximum number of items per user, 0 for unlimited
+	MaxBatchSize         int    `config:"max_batch_size"`         // Maximum number of operations in a batch request
+	MaxImportSize        int64  `config:"max_import_size"`        // Maximum size of an item import in bytes, 0 for unlimited
+	MaxItemTags          int    `config:"max_item_tags"`          // Maximum number of tags attached to a single item
+	MaxMetadataSize      int    `config:"max_metadata_size"`      // Maximum size of an item's metadata in bytes, 0 for unlimited
+	MetadataSchema       string `config:"metadata_schema"`        // Path to a JSON Schema item metadata must satisfy, empty to accept any object
+	MaxNameLength        int    `config:"max_name_length"`        // Maximum item name length in characters
+	MaxDescriptionLength int    `config:"max_description_length"` // Maximum item description length in characters
 }
 
 // Defaults provides default configuration values for API settings
 func (a APIConfig) Defaults() map[string]any {
 	return map[string]any{
-		"items_per_page": 10,  // Default page size
-		"search_limit":   100, // Default search results limit
+		"items_per_page":         10,       // Default page size
+		"search_limit":           100,      // Default search results per page limit
+		"user_max_items":         0,        // No per-user quota by default
+		"max_batch_size":         100,      // Default batch request size limit
+		"max_import_size":        64 << 20, // Default import size limit of 64 MiB
+		"max_item_tags":          20,       // Default tags per item limit
+		"max_metadata_size":      16 << 10, // Default metadata size limit of 16 KiB
+		"metadata_schema":        "",       // No metadata schema by default
+		"max_name_length":        255,      // Matches the items.name column size
+		"max_description_length": 4096,     // Default description length limit
 	}
 }
 
 // Defaults provides the default configuration values
 func (c Config) Defaults() map[string]any {
 	return map[string]any{
-		"storage_path":  "data/template",
-		"max_items":     1000,
-		"cache_enabled": true,
+		"storage_path":         "data/template",
+		"max_items":            1000,
+		"cache_enabled":        true,
+		"trash_retention_days": 30,
+		"max_upload_size":      1 << 30,
+		"store_memory_limit":   4 << 20,
 		"api": map[string]any{
-			"items_per_page": 10,
-			"search_limit":   100,
-			"subdomain":      "template-plugin",
+			"items_per_page":         10,
+			"search_limit":           100,
+			"user_max_items":         0,
+			"max_batch_size":         100,
+			"subdomain":              "template-plugin",
+			"max_import_size":        64 << 20,
+			"max_item_tags":          20,
+			"max_metadata_size":      16 << 10,
+			"metadata_schema":        "",
+			"max_name_length":        255,
+			"max_description_length": 4096,
 		},
 	}
 }
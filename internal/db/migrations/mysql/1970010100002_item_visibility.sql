@@ -0,0 +1,14 @@
+-- Item visibility for the template plugin
+-- This migration adds a visibility level to every item so that private
+-- items can be hidden from public listings and search
+--
+-- Usage:
+-- This migration runs automatically after the ownership migration.
+-- Existing items default to public, matching their previous behaviour.
+--
+-- Columns:
+-- items.visibility: One of public, unlisted or private
+
+ALTER TABLE items
+    ADD COLUMN visibility VARCHAR(16) NOT NULL DEFAULT 'public' AFTER description, -- Visibility level
+    ADD INDEX idx_items_visibility (visibility);                                   -- Visibility filtering
@@ -0,0 +1,13 @@
+-- Full-text search for the template plugin
+-- This migration adds a FULLTEXT index used to rank item search results
+--
+-- Usage:
+-- This migration runs automatically after the visibility migration.
+-- InnoDB keeps the index in sync with the items table. Words shorter than
+-- innodb_ft_min_token_size (3 by default) are not indexed.
+--
+-- Indexes:
+-- ft_items_name_description: Full-text index over item names and descriptions
+
+ALTER TABLE items
+    ADD FULLTEXT INDEX ft_items_name_description (name, description); -- Ranked search
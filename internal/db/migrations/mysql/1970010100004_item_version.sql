@@ -0,0 +1,13 @@
+-- Item versioning for the template plugin
+-- This migration adds a version counter used for optimistic concurrency
+-- control, so concurrent edits are detected instead of overwriting each other
+--
+-- Usage:
+-- This migration runs automatically after the search migration.
+-- Existing items start at version 1.
+--
+-- Columns:
+-- items.version: Incremented on every change, exposed to clients as the ETag
+
+ALTER TABLE items
+    ADD COLUMN version INT UNSIGNED NOT NULL DEFAULT 1 AFTER visibility; -- Item version
@@ -0,0 +1,24 @@
+-- Item revision history for the template plugin
+-- This migration adds a table recording a snapshot of an item after every
+-- change, so edits can be audited, compared and reverted
+--
+-- Usage:
+-- This migration runs automatically after the versioning migration.
+-- Items that existed before this migration have no recorded history
+-- until they are next changed.
+--
+-- Tables:
+-- item_revisions: One row per item version, keyed by item ID and revision
+
+CREATE TABLE IF NOT EXISTS item_revisions (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,            -- Unique identifier for each revision
+    item_id BIGINT NOT NULL,                         -- Item the revision belongs to
+    revision INT UNSIGNED NOT NULL,                  -- Item version after the change
+    action VARCHAR(16) NOT NULL,                     -- Kind of change that produced the revision
+    actor_id BIGINT UNSIGNED NOT NULL DEFAULT 0,     -- User who made the change
+    name VARCHAR(255) NOT NULL,                      -- Item name at this revision
+    description TEXT,                                -- Item description at this revision
+    visibility VARCHAR(16) NOT NULL,                 -- Item visibility at this revision
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,   -- When the change was made
+    UNIQUE INDEX idx_item_revisions_item_revision (item_id, revision) -- One row per version
+);
@@ -0,0 +1,27 @@
+-- Item tags for the template plugin
+-- This migration adds tags, grouped into categories, and a join table
+-- attaching any number of tags to any number of items
+--
+-- Usage:
+-- This migration runs automatically after the revisions migration.
+-- Existing items start without tags.
+--
+-- Tables:
+-- tags: One row per tag, names are unique and stored in lowercase
+-- item_tags: Joins items to their tags
+
+CREATE TABLE IF NOT EXISTS tags (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,            -- Unique identifier for each tag
+    name VARCHAR(64) NOT NULL,                       -- Lowercase tag name
+    category VARCHAR(64) NOT NULL DEFAULT '',        -- Category the tag is grouped under
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,   -- Creation timestamp
+    UNIQUE INDEX idx_tags_name (name),               -- One tag per name
+    INDEX idx_tags_category (category)               -- Category lookups
+);
+
+CREATE TABLE IF NOT EXISTS item_tags (
+    item_id BIGINT NOT NULL,                         -- Tagged item
+    tag_id BIGINT NOT NULL,                          -- Attached tag
+    PRIMARY KEY (item_id, tag_id),                   -- Each tag is attached at most once
+    INDEX idx_item_tags_tag_id (tag_id)              -- Items by tag
+);
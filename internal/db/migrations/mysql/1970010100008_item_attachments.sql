@@ -0,0 +1,24 @@
+-- Item attachments for the template plugin
+-- This migration adds a table linking files stored by the upload workflow
+-- to the items they were attached to
+--
+-- Usage:
+-- This migration runs automatically after the metadata migration.
+-- Existing items start without attachments.
+--
+-- Tables:
+-- item_attachments: One row per attached file, referencing its upload request and content hash
+
+CREATE TABLE IF NOT EXISTS item_attachments (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,            -- Unique identifier for each attachment
+    item_id BIGINT NOT NULL,                         -- Item the file is attached to
+    upload_id BIGINT UNSIGNED NOT NULL,              -- Upload workflow request storing the file
+    hash VARCHAR(128) NOT NULL,                      -- Base58 multihash of the file content
+    name VARCHAR(255) NOT NULL,                      -- Original file name
+    size BIGINT UNSIGNED NOT NULL,                   -- File size in bytes
+    uploader_id BIGINT UNSIGNED NOT NULL,            -- User who attached the file
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,   -- When the file was attached
+    INDEX idx_item_attachments_item_id (item_id),    -- Attachments by item
+    INDEX idx_item_attachments_upload_id (upload_id),-- Attachments by upload
+    INDEX idx_item_attachments_hash (hash)           -- Attachments by content
+);
@@ -0,0 +1,26 @@
+-- Upload progress for the template plugin
+-- This migration adds a table recording the progress of uploads, so every
+-- portal node reports the same progress for an upload
+--
+-- Usage:
+-- This migration runs automatically after the attachments migration.
+-- Uploads started before it report their workflow status only.
+--
+-- Tables:
+-- upload_progress: One row per upload ID, updated as the upload moves through its phases
+
+CREATE TABLE IF NOT EXISTS upload_progress (
+    id VARCHAR(64) PRIMARY KEY,                      -- Upload ID, a workflow request ID or a tus upload ID
+    request_id BIGINT UNSIGNED NOT NULL DEFAULT 0,   -- Upload workflow request, zero until the data has been received
+    user_id BIGINT UNSIGNED NOT NULL,                -- User who uploaded the data
+    hash VARCHAR(128),                               -- Base58 multihash of the content
+    phase VARCHAR(16) NOT NULL,                      -- Current phase of the upload
+    size BIGINT UNSIGNED NOT NULL,                   -- Total size of the upload in bytes
+    uploaded BIGINT UNSIGNED NOT NULL DEFAULT 0,     -- Number of bytes received so far
+    processed BIGINT UNSIGNED NOT NULL DEFAULT 0,    -- Number of bytes the current phase has processed
+    error VARCHAR(1024),                             -- Why the upload failed
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,   -- When the upload started
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,   -- When the progress was last persisted
+    INDEX idx_upload_progress_request_id (request_id), -- Progress by workflow request
+    INDEX idx_upload_progress_updated_at (updated_at)  -- Expiry of finished uploads
+);
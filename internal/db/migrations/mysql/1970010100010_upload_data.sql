@@ -0,0 +1,18 @@
+-- Upload data references for the template plugin
+-- This migration adds a table counting the uploads using each piece of temporary
+-- upload data, so the data can be kept in storage shared by all portal nodes
+--
+-- Usage:
+-- This migration runs automatically after the upload progress migration.
+-- Uploads staged on a node's local disk before it have to be uploaded again.
+--
+-- Tables:
+-- upload_data: One row per content hash, removed along with the data by the last upload using it
+
+CREATE TABLE IF NOT EXISTS upload_data (
+    hash VARCHAR(128) PRIMARY KEY,                   -- Hex multihash of the content
+    storage_key VARCHAR(64) NOT NULL,                -- Temporary storage key holding the data
+    refs INT NOT NULL DEFAULT 0,                     -- Number of uploads using the data
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,   -- When the data was staged
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP    -- When a reference was last taken or dropped
+);
@@ -0,0 +1,14 @@
+-- Quota locks for the template plugin
+-- This migration adds the rows locked while checking item quotas, so concurrent
+-- requests can't create items beyond a quota
+--
+-- Usage:
+-- This migration runs automatically after the item revision tags migration.
+-- Rows are created on first use.
+--
+-- Tables:
+-- quota_locks: One row per user with a quota, and row zero for the portal wide limit
+
+CREATE TABLE IF NOT EXISTS quota_locks (
+    owner_id BIGINT UNSIGNED PRIMARY KEY -- User whose quota the row guards, zero for the portal wide limit
+);
@@ -0,0 +1,25 @@
+-- Import progress for the template plugin
+-- This migration adds a table recording the progress of item imports, so every
+-- portal node reports the same progress for an import
+--
+-- Usage:
+-- This migration runs automatically after the quota locks migration.
+-- Imports started before it report their workflow status only.
+--
+-- Tables:
+-- import_progress: One row per import, updated as the import workflow processes rows
+
+CREATE TABLE IF NOT EXISTS import_progress (
+    id VARCHAR(64) PRIMARY KEY,                      -- Import ID, the import workflow request ID
+    user_id BIGINT UNSIGNED NOT NULL,                -- User who started the import
+    format VARCHAR(16) NOT NULL,                     -- Transfer format of the import data
+    `rows` INT NOT NULL DEFAULT 0,                   -- Number of rows processed so far
+    created INT NOT NULL DEFAULT 0,                  -- Number of items created so far
+    failed INT NOT NULL DEFAULT 0,                   -- Number of rows rejected so far
+    errors JSON,                                     -- The first rejected rows and why
+    error VARCHAR(1024),                             -- Why the import stopped early
+    completed BOOLEAN NOT NULL DEFAULT FALSE,        -- Whether the import has finished
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,   -- When the import started
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,   -- When the progress was last persisted
+    INDEX idx_import_progress_updated_at (updated_at) -- Expiry of finished imports
+);
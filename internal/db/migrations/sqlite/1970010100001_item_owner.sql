@@ -0,0 +1,16 @@
+-- Item ownership for the template plugin
+-- This migration adds an owner to every item so that modifications can be
+-- restricted to the user who created the item
+--
+-- Usage:
+-- This migration runs automatically after the initial schema. Items that
+-- existed before this migration are left with owner_id 0, which means
+-- they can only be modified by administrators.
+--
+-- Columns:
+-- items.owner_id: ID of the portal user who created the item
+-- SQLite version of the schema
+
+ALTER TABLE items ADD COLUMN owner_id INTEGER NOT NULL DEFAULT 0; -- Owning user ID
+
+CREATE INDEX IF NOT EXISTS idx_items_owner_id ON items (owner_id); -- Per-user lookups
@@ -0,0 +1,15 @@
+-- Item visibility for the template plugin
+-- This migration adds a visibility level to every item so that private
+-- items can be hidden from public listings and search
+--
+-- Usage:
+-- This migration runs automatically after the ownership migration.
+-- Existing items default to public, matching their previous behaviour.
+--
+-- Columns:
+-- items.visibility: One of public, unlisted or private
+-- SQLite version of the schema
+
+ALTER TABLE items ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public'; -- Visibility level
+
+CREATE INDEX IF NOT EXISTS idx_items_visibility ON items (visibility); -- Visibility filtering
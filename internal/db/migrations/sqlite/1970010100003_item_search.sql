@@ -0,0 +1,33 @@
+-- Full-text search for the template plugin
+-- This migration adds an FTS5 index used to rank item search results
+--
+-- Usage:
+-- This migration runs automatically after the visibility migration.
+-- The index is an external content table over items, kept in sync by
+-- triggers and rebuilt from any existing rows.
+--
+-- Tables:
+-- items_fts: FTS5 index over item names and descriptions
+-- SQLite version of the schema
+
+CREATE VIRTUAL TABLE IF NOT EXISTS items_fts USING fts5(
+    name,                                         -- Indexed item name
+    description,                                  -- Indexed item description
+    content='items',                              -- Read column values from items
+    content_rowid='id'                            -- Rows are keyed by item ID
+);
+
+INSERT INTO items_fts(items_fts) VALUES ('rebuild');
+
+CREATE TRIGGER IF NOT EXISTS items_fts_insert AFTER INSERT ON items BEGIN
+    INSERT INTO items_fts(rowid, name, description) VALUES (new.id, new.name, new.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS items_fts_delete AFTER DELETE ON items BEGIN
+    INSERT INTO items_fts(items_fts, rowid, name, description) VALUES ('delete', old.id, old.name, old.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS items_fts_update AFTER UPDATE ON items BEGIN
+    INSERT INTO items_fts(items_fts, rowid, name, description) VALUES ('delete', old.id, old.name, old.description);
+    INSERT INTO items_fts(rowid, name, description) VALUES (new.id, new.name, new.description);
+END;
@@ -0,0 +1,26 @@
+-- Item revision history for the template plugin
+-- This migration adds a table recording a snapshot of an item after every
+-- change, so edits can be audited, compared and reverted
+--
+-- Usage:
+-- This migration runs automatically after the versioning migration.
+-- Items that existed before this migration have no recorded history
+-- until they are next changed.
+--
+-- Tables:
+-- item_revisions: One row per item version, keyed by item ID and revision
+-- SQLite version of the schema
+
+CREATE TABLE IF NOT EXISTS item_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,          -- Unique identifier for each revision
+    item_id INTEGER NOT NULL,                      -- Item the revision belongs to
+    revision INTEGER NOT NULL,                     -- Item version after the change
+    action TEXT NOT NULL,                          -- Kind of change that produced the revision
+    actor_id INTEGER NOT NULL DEFAULT 0,           -- User who made the change
+    name TEXT NOT NULL,                            -- Item name at this revision
+    description TEXT,                              -- Item description at this revision
+    visibility TEXT NOT NULL,                      -- Item visibility at this revision
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP  -- When the change was made
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_item_revisions_item_revision ON item_revisions (item_id, revision); -- One row per version
@@ -0,0 +1,30 @@
+-- Item tags for the template plugin
+-- This migration adds tags, grouped into categories, and a join table
+-- attaching any number of tags to any number of items
+--
+-- Usage:
+-- This migration runs automatically after the revisions migration.
+-- Existing items start without tags.
+--
+-- Tables:
+-- tags: One row per tag, names are unique and stored in lowercase
+-- item_tags: Joins items to their tags
+-- SQLite version of the schema
+
+CREATE TABLE IF NOT EXISTS tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,          -- Unique identifier for each tag
+    name TEXT NOT NULL,                            -- Lowercase tag name
+    category TEXT NOT NULL DEFAULT '',             -- Category the tag is grouped under
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP  -- Creation timestamp
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_name ON tags (name);         -- One tag per name
+CREATE INDEX IF NOT EXISTS idx_tags_category ON tags (category);        -- Category lookups
+
+CREATE TABLE IF NOT EXISTS item_tags (
+    item_id INTEGER NOT NULL,                      -- Tagged item
+    tag_id INTEGER NOT NULL,                       -- Attached tag
+    PRIMARY KEY (item_id, tag_id)                  -- Each tag is attached at most once
+);
+
+CREATE INDEX IF NOT EXISTS idx_item_tags_tag_id ON item_tags (tag_id); -- Items by tag
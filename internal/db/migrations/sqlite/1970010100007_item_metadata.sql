@@ -0,0 +1,16 @@
+-- Item metadata for the template plugin
+-- This migration adds a JSON document of arbitrary metadata to items and
+-- their revisions, so metadata changes show up in an item's history
+--
+-- Usage:
+-- This migration runs automatically after the tags migration.
+-- Existing items and revisions start without metadata.
+--
+-- Columns:
+-- items.metadata: JSON object describing the item, queried with json_extract
+-- item_revisions.metadata: Item metadata at the revision
+-- SQLite version of the schema
+
+ALTER TABLE items ADD COLUMN metadata JSON; -- Item metadata
+
+ALTER TABLE item_revisions ADD COLUMN metadata JSON; -- Item metadata at the revision
@@ -0,0 +1,26 @@
+-- Item attachments for the template plugin
+-- This migration adds a table linking files stored by the upload workflow
+-- to the items they were attached to
+--
+-- Usage:
+-- This migration runs automatically after the metadata migration.
+-- Existing items start without attachments.
+--
+-- Tables:
+-- item_attachments: One row per attached file, referencing its upload request and content hash
+-- SQLite version of the schema
+
+CREATE TABLE IF NOT EXISTS item_attachments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,          -- Unique identifier for each attachment
+    item_id INTEGER NOT NULL,                      -- Item the file is attached to
+    upload_id INTEGER NOT NULL,                    -- Upload workflow request storing the file
+    hash TEXT NOT NULL,                            -- Base58 multihash of the file content
+    name TEXT NOT NULL,                            -- Original file name
+    size INTEGER NOT NULL,                         -- File size in bytes
+    uploader_id INTEGER NOT NULL,                  -- User who attached the file
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP  -- When the file was attached
+);
+
+CREATE INDEX IF NOT EXISTS idx_item_attachments_item_id ON item_attachments (item_id);     -- Attachments by item
+CREATE INDEX IF NOT EXISTS idx_item_attachments_upload_id ON item_attachments (upload_id); -- Attachments by upload
+CREATE INDEX IF NOT EXISTS idx_item_attachments_hash ON item_attachments (hash);           -- Attachments by content
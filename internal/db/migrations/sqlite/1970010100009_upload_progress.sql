@@ -0,0 +1,28 @@
+-- Upload progress for the template plugin
+-- This migration adds a table recording the progress of uploads, so every
+-- portal node reports the same progress for an upload
+--
+-- Usage:
+-- This migration runs automatically after the attachments migration.
+-- Uploads started before it report their workflow status only.
+--
+-- Tables:
+-- upload_progress: One row per upload ID, updated as the upload moves through its phases
+-- SQLite version of the schema
+
+CREATE TABLE IF NOT EXISTS upload_progress (
+    id TEXT PRIMARY KEY,                           -- Upload ID, a workflow request ID or a tus upload ID
+    request_id INTEGER NOT NULL DEFAULT 0,         -- Upload workflow request, zero until the data has been received
+    user_id INTEGER NOT NULL,                      -- User who uploaded the data
+    hash TEXT,                                     -- Base58 multihash of the content
+    phase TEXT NOT NULL,                           -- Current phase of the upload
+    size INTEGER NOT NULL,                         -- Total size of the upload in bytes
+    uploaded INTEGER NOT NULL DEFAULT 0,           -- Number of bytes received so far
+    processed INTEGER NOT NULL DEFAULT 0,          -- Number of bytes the current phase has processed
+    error TEXT,                                    -- Why the upload failed
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP, -- When the upload started
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP  -- When the progress was last persisted
+);
+
+CREATE INDEX IF NOT EXISTS idx_upload_progress_request_id ON upload_progress (request_id); -- Progress by workflow request
+CREATE INDEX IF NOT EXISTS idx_upload_progress_updated_at ON upload_progress (updated_at); -- Expiry of finished uploads
@@ -0,0 +1,13 @@
+-- Item revision tags for the template plugin
+-- This migration records the names of an item's tags in its revisions, so tag
+-- changes show up in an item's history
+--
+-- Usage:
+-- This migration runs automatically after the upload data migration.
+-- Existing revisions start without tags.
+--
+-- Columns:
+-- item_revisions.tags: JSON array of the item's tag names at the revision
+-- SQLite version of the schema
+
+ALTER TABLE item_revisions ADD COLUMN tags JSON; -- Item tag names at the revision
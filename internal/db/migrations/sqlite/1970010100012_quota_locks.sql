@@ -0,0 +1,15 @@
+-- Quota locks for the template plugin
+-- This migration adds the rows locked while checking item quotas, so concurrent
+-- requests can't create items beyond a quota
+--
+-- Usage:
+-- This migration runs automatically after the item revision tags migration.
+-- Rows are created on first use.
+--
+-- Tables:
+-- quota_locks: One row per user with a quota, and row zero for the portal wide limit
+-- SQLite version of the schema
+
+CREATE TABLE IF NOT EXISTS quota_locks (
+    owner_id INTEGER PRIMARY KEY -- User whose quota the row guards, zero for the portal wide limit
+);
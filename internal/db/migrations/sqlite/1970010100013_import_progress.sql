@@ -0,0 +1,27 @@
+-- Import progress for the template plugin
+-- This migration adds a table recording the progress of item imports, so every
+-- portal node reports the same progress for an import
+--
+-- Usage:
+-- This migration runs automatically after the quota locks migration.
+-- Imports started before it report their workflow status only.
+--
+-- Tables:
+-- import_progress: One row per import, updated as the import workflow processes rows
+-- SQLite version of the schema
+
+CREATE TABLE IF NOT EXISTS import_progress (
+    id TEXT PRIMARY KEY,                           -- Import ID, the import workflow request ID
+    user_id INTEGER NOT NULL,                      -- User who started the import
+    format TEXT NOT NULL,                          -- Transfer format of the import data
+    rows INTEGER NOT NULL DEFAULT 0,               -- Number of rows processed so far
+    created INTEGER NOT NULL DEFAULT 0,            -- Number of items created so far
+    failed INTEGER NOT NULL DEFAULT 0,             -- Number of rows rejected so far
+    errors JSON,                                   -- The first rejected rows and why
+    error TEXT,                                    -- Why the import stopped early
+    completed BOOLEAN NOT NULL DEFAULT 0,          -- Whether the import has finished
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP, -- When the import started
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP  -- When the progress was last persisted
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_progress_updated_at ON import_progress (updated_at); -- Expiry of finished imports
@@ -0,0 +1,16 @@
+-- Purge runs for the template plugin
+-- This migration adds the row recording when expired items were last purged from the
+-- trash, so only one portal node purges them per interval
+--
+-- Usage:
+-- This migration runs automatically after the import progress migration.
+-- The row is created on first use.
+--
+-- Tables:
+-- purge_runs: A single row claimed by the node running the purge
+-- SQLite version of the schema
+
+CREATE TABLE IF NOT EXISTS purge_runs (
+    id INTEGER PRIMARY KEY, -- Always one, there's a single purge schedule
+    ran_at DATETIME         -- When the last purge was claimed
+);
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// ImportProgress records how far an item import has come
+// Rows are created by the portal node accepting the import and updated by the node
+// running its workflow, so every node reports the same progress.
+type ImportProgress struct {
+	ID        string         `json:"id" gorm:"primarykey;size:64"`   // Import ID, the import workflow request ID
+	UserID    uint           `json:"user_id" gorm:"not null"`        // ID of the user who started the import
+	Format    string         `json:"format" gorm:"not null;size:16"` // Transfer format of the import data
+	Rows      int            `json:"rows" gorm:"not null"`           // Number of rows processed so far
+	Created   int            `json:"created" gorm:"not null"`        // Number of items created so far
+	Failed    int            `json:"failed" gorm:"not null"`         // Number of rows rejected so far
+	Errors    datatypes.JSON `json:"errors,omitempty"`               // The first rejected rows and why, as a JSON array
+	Error     string         `json:"error" gorm:"size:1024"`         // Why the import stopped early, empty unless it did
+	Completed bool           `json:"completed" gorm:"not null"`      // Whether the import has finished
+	CreatedAt time.Time      `json:"created_at"`                     // When the import started
+	UpdatedAt time.Time      `json:"updated_at" gorm:"index"`        // When the progress was last persisted
+}
+
+// TableName keeps the table name singular, as progress is uncountable
+func (ImportProgress) TableName() string {
+	return "import_progress"
+}
@@ -2,13 +2,35 @@
 package models
 
 import (
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
+// Item visibility levels
+const (
+	VisibilityPublic   = "public"   // Listed, searchable and readable by everyone
+	VisibilityUnlisted = "unlisted" // Readable by ID but hidden from listings and search
+	VisibilityPrivate  = "private"  // Only visible to the owner
+)
+
 // Item represents a basic item in the system
 // It demonstrates a simple GORM model with basic fields
 type Item struct {
-	gorm.Model          // Provides ID, CreatedAt, UpdatedAt, DeletedAt fields
-	Name        string  `json:"name" gorm:"not null"`         // Required name field
-	Description string  `json:"description" gorm:"type:text"` // Optional description field
+	gorm.Model                 // Provides ID, CreatedAt, UpdatedAt, DeletedAt fields
+	OwnerID     uint           `json:"owner_id" gorm:"not null;default:0;index"`        // ID of the user who created the item
+	Name        string         `json:"name" gorm:"not null"`                            // Required name field
+	Description string         `json:"description" gorm:"type:text"`                    // Optional description field
+	Visibility  string         `json:"visibility" gorm:"not null;default:public;index"` // Who can see the item
+	Version     uint           `json:"version" gorm:"not null;default:1"`               // Incremented on every change, used for optimistic concurrency
+	Metadata    datatypes.JSON `json:"metadata,omitempty"`                              // Arbitrary JSON object describing the item
+	Tags        []Tag          `json:"tags,omitempty" gorm:"many2many:item_tags"`       // Tags attached to the item
+}
+
+// ValidVisibility reports whether v is a known visibility level
+func ValidVisibility(v string) bool {
+	switch v {
+	case VisibilityPublic, VisibilityUnlisted, VisibilityPrivate:
+		return true
+	}
+	return false
 }
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// ItemAttachment links an uploaded file to an item
+// The file itself is stored by the protocol's upload workflow. The attachment
+// records the workflow request that stores it and the file's content hash, so the
+// same content attached twice is only stored once.
+type ItemAttachment struct {
+	ID         uint      `json:"id" gorm:"primarykey"`                // Unique identifier for the attachment
+	ItemID     uint      `json:"item_id" gorm:"not null;index"`       // Item the file is attached to
+	UploadID   uint      `json:"upload_id" gorm:"not null;index"`     // Upload workflow request storing the file, zero until it started
+	Hash       string    `json:"hash" gorm:"not null;size:128;index"` // Base58 multihash of the file content
+	Name       string    `json:"name" gorm:"not null;size:255"`       // Original file name
+	Size       uint64    `json:"size" gorm:"not null"`                // File size in bytes
+	UploaderID uint      `json:"uploader_id" gorm:"not null"`         // ID of the user who attached the file
+	CreatedAt  time.Time `json:"created_at"`                          // When the file was attached
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Item revision actions
+const (
+	RevisionActionCreate  = "create"  // The item was created
+	RevisionActionUpdate  = "update"  // The item was updated or patched
+	RevisionActionDelete  = "delete"  // The item was moved to the trash
+	RevisionActionRestore = "restore" // The item was restored from the trash
+	RevisionActionRevert  = "revert"  // The item was reverted to an earlier revision
+)
+
+// ItemRevision is a snapshot of an item taken after a change
+// Revisions are numbered by the item version they record, so every version of
+// an item has exactly one revision.
+type ItemRevision struct {
+	ID          uint           `json:"id" gorm:"primarykey"`                                                  // Unique identifier for the revision
+	ItemID      uint           `json:"item_id" gorm:"not null;uniqueIndex:idx_item_revisions_item_revision"`  // Item the revision belongs to
+	Revision    uint           `json:"revision" gorm:"not null;uniqueIndex:idx_item_revisions_item_revision"` // Item version after the change
+	Action      string         `json:"action" gorm:"not null"`                                                // Kind of change, one of the RevisionAction constants
+	ActorID     uint           `json:"actor_id" gorm:"not null;default:0"`                                    // ID of the user who made the change
+	Name        string         `json:"name" gorm:"not null"`                                                  // Item name at this revision
+	Description string         `json:"description" gorm:"type:text"`                                          // Item description at this revision
+	Visibility  string         `json:"visibility" gorm:"not null"`                                            // Item visibility at this revision
+	Metadata    datatypes.JSON `json:"metadata,omitempty"`                                                    // Item metadata at this revision
+	Tags        datatypes.JSON `json:"tags,omitempty"`                                                        // Names of the item's tags at this revision, as a JSON array
+	CreatedAt   time.Time      `json:"created_at"`                                                            // When the change was made
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// PurgeRun records when expired items were last purged from the trash
+// Every portal node schedules the purge, and the node moving the recorded time forward
+// runs it, so a purge isn't repeated by the other nodes. The row is created on first use.
+type PurgeRun struct {
+	ID    uint       `json:"id" gorm:"primarykey;autoIncrement:false"` // Always one, there's a single purge schedule
+	RanAt *time.Time `json:"ran_at"`                                   // When the last purge was claimed, nil before the first one
+}
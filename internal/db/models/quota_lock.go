@@ -0,0 +1,8 @@
+package models
+
+// QuotaLock is a row locked by transactions checking an item quota
+// Locking it serializes the quota check and the item insert of concurrent requests,
+// so they can't both pass a check against the same count. Rows are created on first use.
+type QuotaLock struct {
+	OwnerID uint `json:"owner_id" gorm:"primarykey;autoIncrement:false"` // User whose quota the row guards, zero for the portal wide limit
+}
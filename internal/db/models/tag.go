@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// MaxTagLength is the longest tag name or category, matching the tags column sizes
+const MaxTagLength = 64
+
+// Tag is a label that can be attached to any number of items
+// Tags are grouped by category for faceted browsing, an empty category
+// leaves the tag uncategorised. Names are stored in lowercase.
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primarykey"`                     // Unique identifier for the tag
+	Name      string    `json:"name" gorm:"not null;size:64;uniqueIndex"` // Unique lowercase tag name
+	Category  string    `json:"category" gorm:"not null;size:64;index"`   // Category the tag is grouped under
+	CreatedAt time.Time `json:"created_at"`                               // When the tag was created
+}
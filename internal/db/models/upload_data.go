@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// UploadData records the temporary data of uploads waiting for the upload workflow
+// Identical uploads share their data, which is kept in the portal's shared temporary
+// storage until the last upload using it has been stored. References are counted here
+// rather than in memory, so any node can release them and they survive restarts.
+// Item imports keep their data the same way, recorded under the content hash followed
+// by the import format.
+type UploadData struct {
+	Hash       string    `json:"hash" gorm:"primarykey;size:128"`     // Hex multihash of the content, with the format appended for imports
+	StorageKey string    `json:"storage_key" gorm:"not null;size:64"` // Temporary storage key holding the data
+	Refs       int       `json:"refs" gorm:"not null"`                // Number of uploads using the data
+	CreatedAt  time.Time `json:"created_at"`                          // When the data was staged
+	UpdatedAt  time.Time `json:"updated_at"`                          // When a reference was last taken or dropped
+}
+
+// TableName keeps the table name singular, as data is uncountable
+func (UploadData) TableName() string {
+	return "upload_data"
+}
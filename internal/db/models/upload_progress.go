@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// UploadProgress records how far an upload has come
+// Rows are written by the portal node handling the upload, so every node reports
+// the same progress. Resumable uploads have a row under their tus ID while they
+// receive data, which points to the row of the upload workflow request afterwards.
+type UploadProgress struct {
+	ID        string    `json:"id" gorm:"primarykey;size:64"`     // Upload ID, a workflow request ID or a tus upload ID
+	RequestID uint      `json:"request_id" gorm:"not null;index"` // Upload workflow request, zero until the data has been received
+	UserID    uint      `json:"user_id" gorm:"not null"`          // ID of the user who uploaded the data
+	Hash      string    `json:"hash" gorm:"size:128"`             // Base58 multihash of the content, empty until it has been hashed
+	Phase     string    `json:"phase" gorm:"not null;size:16"`    // Current phase of the upload
+	Size      uint64    `json:"size" gorm:"not null"`             // Total size of the upload in bytes
+	Uploaded  uint64    `json:"uploaded" gorm:"not null"`         // Number of bytes received so far
+	Processed uint64    `json:"processed" gorm:"not null"`        // Number of bytes the current phase has processed
+	Error     string    `json:"error" gorm:"size:1024"`           // Why the upload failed, empty unless it did
+	CreatedAt time.Time `json:"created_at"`                       // When the upload started
+	UpdatedAt time.Time `json:"updated_at" gorm:"index"`          // When the progress was last persisted
+}
+
+// TableName keeps the table name singular, as progress is uncountable
+func (UploadProgress) TableName() string {
+	return "upload_progress"
+}
@@ -0,0 +1,431 @@
+// Package jsonschema implements validation of JSON documents against a subset of JSON Schema
+//
+// Supported keywords: type, enum, const, properties, required, additionalProperties,
+// minProperties, maxProperties, items, minItems, maxItems, minLength, maxLength, pattern,
+// minimum, maximum, exclusiveMinimum and exclusiveMaximum. Other keywords are ignored,
+// so schemas written for a full validator still load, but are only partially enforced.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is a parsed JSON Schema
+type Schema struct {
+	Never                bool               // Whether the schema is false and rejects every value
+	Types                []string           // Allowed types, empty for any
+	Enum                 []any              // Allowed values, empty for any
+	Const                *any               // The only allowed value, nil for any
+	Properties           map[string]*Schema // Schemas of known object properties
+	Required             []string           // Properties that must be present
+	AdditionalProperties *Schema            // Schema of unknown properties, nil for any
+	NoAdditional         bool               // Whether unknown properties are rejected
+	MinProperties        *int               // Minimum number of object properties
+	MaxProperties        *int               // Maximum number of object properties
+	Items                *Schema            // Schema of every array element, nil for any
+	MinItems             *int               // Minimum number of array elements
+	MaxItems             *int               // Maximum number of array elements
+	MinLength            *int               // Minimum string length in characters
+	MaxLength            *int               // Maximum string length in characters
+	Pattern              *regexp.Regexp     // Pattern strings must match
+	Minimum              *float64           // Inclusive lower bound for numbers
+	Maximum              *float64           // Inclusive upper bound for numbers
+	ExclusiveMinimum     *float64           // Exclusive lower bound for numbers
+	ExclusiveMaximum     *float64           // Exclusive upper bound for numbers
+}
+
+// Errors maps the path of each invalid value to a description of what is wrong with it.
+// Paths join object keys and array indexes with dots, the document itself has an empty path.
+type Errors map[string]string
+
+// Parse parses a JSON Schema document
+func Parse(data []byte) (*Schema, error) {
+	var raw any
+	if err := decode(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	return parseSchema(raw, "")
+}
+
+// decode unmarshals JSON keeping numbers as json.Number so integers are told apart from floats
+func decode(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return fmt.Errorf("unexpected data after JSON value")
+	}
+	return nil
+}
+
+// parseSchema converts a decoded schema object into a Schema
+func parseSchema(raw any, path string) (*Schema, error) {
+	// true accepts everything, false accepts nothing
+	if b, ok := raw.(bool); ok {
+		return &Schema{Never: !b}, nil
+	}
+
+	obj, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("schema at %q must be an object or a boolean", path)
+	}
+
+	s := &Schema{}
+	var err error
+
+	switch t := obj["type"].(type) {
+	case nil:
+	case string:
+		s.Types = []string{t}
+	case []any:
+		for _, name := range t {
+			str, ok := name.(string)
+			if !ok {
+				return nil, fmt.Errorf("type at %q must be a string or an array of strings", path)
+			}
+			s.Types = append(s.Types, str)
+		}
+	default:
+		return nil, fmt.Errorf("type at %q must be a string or an array of strings", path)
+	}
+
+	if enum, ok := obj["enum"]; ok {
+		values, ok := enum.([]any)
+		if !ok {
+			return nil, fmt.Errorf("enum at %q must be an array", path)
+		}
+		s.Enum = values
+	}
+
+	if c, ok := obj["const"]; ok {
+		s.Const = &c
+	}
+
+	if props, ok := obj["properties"]; ok {
+		propMap, ok := props.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("properties at %q must be an object", path)
+		}
+		s.Properties = make(map[string]*Schema, len(propMap))
+		for name, prop := range propMap {
+			if s.Properties[name], err = parseSchema(prop, join(path, name)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if required, ok := obj["required"]; ok {
+		names, ok := required.([]any)
+		if !ok {
+			return nil, fmt.Errorf("required at %q must be an array", path)
+		}
+		for _, name := range names {
+			str, ok := name.(string)
+			if !ok {
+				return nil, fmt.Errorf("required at %q must only contain strings", path)
+			}
+			s.Required = append(s.Required, str)
+		}
+	}
+
+	switch additional := obj["additionalProperties"].(type) {
+	case nil:
+	case bool:
+		s.NoAdditional = !additional
+	default:
+		if s.AdditionalProperties, err = parseSchema(additional, join(path, "*")); err != nil {
+			return nil, err
+		}
+	}
+
+	if items, ok := obj["items"]; ok {
+		if s.Items, err = parseSchema(items, join(path, "*")); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.Pattern, err = parsePattern(obj, path); err != nil {
+		return nil, err
+	}
+
+	counts := []struct {
+		keyword string
+		dest    **int
+	}{
+		{"minProperties", &s.MinProperties},
+		{"maxProperties", &s.MaxProperties},
+		{"minItems", &s.MinItems},
+		{"maxItems", &s.MaxItems},
+		{"minLength", &s.MinLength},
+		{"maxLength", &s.MaxLength},
+	}
+	for _, c := range counts {
+		if *c.dest, err = parseCount(obj, c.keyword, path); err != nil {
+			return nil, err
+		}
+	}
+
+	bounds := []struct {
+		keyword string
+		dest    **float64
+	}{
+		{"minimum", &s.Minimum},
+		{"maximum", &s.Maximum},
+		{"exclusiveMinimum", &s.ExclusiveMinimum},
+		{"exclusiveMaximum", &s.ExclusiveMaximum},
+	}
+	for _, b := range bounds {
+		if *b.dest, err = parseNumber(obj, b.keyword, path); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// parsePattern compiles the pattern keyword of a schema object
+func parsePattern(obj map[string]any, path string) (*regexp.Regexp, error) {
+	raw, ok := obj["pattern"]
+	if !ok {
+		return nil, nil
+	}
+
+	pattern, ok := raw.(string)
+	if !ok {
+		return nil, fmt.Errorf("pattern at %q must be a string", path)
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("pattern at %q: %w", path, err)
+	}
+
+	return re, nil
+}
+
+// parseCount reads a non-negative integer keyword of a schema object
+func parseCount(obj map[string]any, keyword string, path string) (*int, error) {
+	raw, ok := obj[keyword]
+	if !ok {
+		return nil, nil
+	}
+
+	num, ok := raw.(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("%s at %q must be a number", keyword, path)
+	}
+
+	n, err := num.Int64()
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("%s at %q must be a non-negative integer", keyword, path)
+	}
+
+	count := int(n)
+	return &count, nil
+}
+
+// parseNumber reads a numeric keyword of a schema object
+func parseNumber(obj map[string]any, keyword string, path string) (*float64, error) {
+	raw, ok := obj[keyword]
+	if !ok {
+		return nil, nil
+	}
+
+	num, ok := raw.(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("%s at %q must be a number", keyword, path)
+	}
+
+	f, err := num.Float64()
+	if err != nil {
+		return nil, fmt.Errorf("%s at %q: %w", keyword, path, err)
+	}
+
+	return &f, nil
+}
+
+// Validate checks a JSON document against the schema and returns the failures keyed by path.
+// A document that is not valid JSON is reported at the empty path.
+func (s *Schema) Validate(data []byte) Errors {
+	var doc any
+	if err := decode(data, &doc); err != nil {
+		return Errors{"": "must be valid JSON"}
+	}
+
+	errs := Errors{}
+	s.validate(doc, "", errs)
+	return errs
+}
+
+// validate checks a decoded value against the schema, recording failures in errs
+func (s *Schema) validate(value any, path string, errs Errors) {
+	if s.Never {
+		errs[path] = "is not allowed"
+		return
+	}
+
+	if len(s.Types) > 0 && !slices.ContainsFunc(s.Types, func(t string) bool { return hasType(value, t) }) {
+		errs[path] = fmt.Sprintf("must be of type %s", strings.Join(s.Types, " or "))
+		return
+	}
+
+	if s.Enum != nil && !slices.ContainsFunc(s.Enum, func(e any) bool { return equal(value, e) }) {
+		errs[path] = "must be one of the allowed values"
+		return
+	}
+
+	if s.Const != nil && !equal(value, *s.Const) {
+		errs[path] = "must be the allowed value"
+		return
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		s.validateObject(v, path, errs)
+	case []any:
+		s.validateArray(v, path, errs)
+	case string:
+		s.validateString(v, path, errs)
+	case json.Number:
+		s.validateNumber(v, path, errs)
+	}
+}
+
+func (s *Schema) validateObject(obj map[string]any, path string, errs Errors) {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			errs[join(path, name)] = "is required"
+		}
+	}
+
+	if s.MinProperties != nil && len(obj) < *s.MinProperties {
+		errs[path] = fmt.Sprintf("must have at least %d properties", *s.MinProperties)
+	}
+	if s.MaxProperties != nil && len(obj) > *s.MaxProperties {
+		errs[path] = fmt.Sprintf("must have at most %d properties", *s.MaxProperties)
+	}
+
+	// Visit properties in a stable order so the same document always yields the same errors
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop, known := s.Properties[name]
+		switch {
+		case known:
+			prop.validate(obj[name], join(path, name), errs)
+		case s.NoAdditional:
+			errs[join(path, name)] = "is not allowed"
+		case s.AdditionalProperties != nil:
+			s.AdditionalProperties.validate(obj[name], join(path, name), errs)
+		}
+	}
+}
+
+func (s *Schema) validateArray(arr []any, path string, errs Errors) {
+	if s.MinItems != nil && len(arr) < *s.MinItems {
+		errs[path] = fmt.Sprintf("must have at least %d items", *s.MinItems)
+	}
+	if s.MaxItems != nil && len(arr) > *s.MaxItems {
+		errs[path] = fmt.Sprintf("must have at most %d items", *s.MaxItems)
+	}
+
+	if s.Items != nil {
+		for i, item := range arr {
+			s.Items.validate(item, join(path, fmt.Sprint(i)), errs)
+		}
+	}
+}
+
+func (s *Schema) validateString(str string, path string, errs Errors) {
+	length := utf8.RuneCountInString(str)
+
+	switch {
+	case s.MinLength != nil && length < *s.MinLength:
+		errs[path] = fmt.Sprintf("must be at least %d characters", *s.MinLength)
+	case s.MaxLength != nil && length > *s.MaxLength:
+		errs[path] = fmt.Sprintf("must be at most %d characters", *s.MaxLength)
+	case s.Pattern != nil && !s.Pattern.MatchString(str):
+		errs[path] = fmt.Sprintf("must match the pattern %s", s.Pattern)
+	}
+}
+
+func (s *Schema) validateNumber(num json.Number, path string, errs Errors) {
+	f, err := num.Float64()
+	if err != nil {
+		errs[path] = "must be a valid number"
+		return
+	}
+
+	switch {
+	case s.Minimum != nil && f < *s.Minimum:
+		errs[path] = fmt.Sprintf("must be at least %v", *s.Minimum)
+	case s.Maximum != nil && f > *s.Maximum:
+		errs[path] = fmt.Sprintf("must be at most %v", *s.Maximum)
+	case s.ExclusiveMinimum != nil && f <= *s.ExclusiveMinimum:
+		errs[path] = fmt.Sprintf("must be greater than %v", *s.ExclusiveMinimum)
+	case s.ExclusiveMaximum != nil && f >= *s.ExclusiveMaximum:
+		errs[path] = fmt.Sprintf("must be less than %v", *s.ExclusiveMaximum)
+	}
+}
+
+// hasType reports whether a decoded value is of the named JSON Schema type
+func hasType(value any, name string) bool {
+	switch v := value.(type) {
+	case nil:
+		return name == "null"
+	case bool:
+		return name == "boolean"
+	case string:
+		return name == "string"
+	case []any:
+		return name == "array"
+	case map[string]any:
+		return name == "object"
+	case json.Number:
+		if name == "number" {
+			return true
+		}
+		if name == "integer" {
+			f, err := v.Float64()
+			return err == nil && f == math.Trunc(f)
+		}
+	}
+	return false
+}
+
+// equal reports whether two decoded values are the same JSON value
+func equal(a any, b any) bool {
+	na, aok := a.(json.Number)
+	nb, bok := b.(json.Number)
+	if aok && bok {
+		fa, errA := na.Float64()
+		fb, errB := nb.Float64()
+		return errA == nil && errB == nil && fa == fb
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// join appends a key to a path
+func join(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
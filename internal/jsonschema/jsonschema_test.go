@@ -0,0 +1,140 @@
+package jsonschema
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testSchema = `{
+	"type": "object",
+	"required": ["sku"],
+	"additionalProperties": false,
+	"properties": {
+		"sku": {"type": "string", "pattern": "^[A-Z]{3}-[0-9]+$"},
+		"color": {"enum": ["red", "green", "blue"]},
+		"weight": {"type": "number", "exclusiveMinimum": 0, "maximum": 1000},
+		"stock": {"type": "integer", "minimum": 0},
+		"labels": {"type": "array", "maxItems": 2, "items": {"type": "string", "maxLength": 5}},
+		"dimensions": {
+			"type": "object",
+			"additionalProperties": {"type": "number"}
+		},
+		"note": {"type": ["string", "null"]}
+	}
+}`
+
+func TestValidate(t *testing.T) {
+	schema, err := Parse([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		doc  string
+		want Errors
+	}{
+		{
+			name: "valid document",
+			doc:  `{"sku":"ABC-1","color":"red","weight":1.5,"stock":3,"labels":["a","b"],"dimensions":{"w":1},"note":null}`,
+			want: Errors{},
+		},
+		{
+			name: "missing required property",
+			doc:  `{"color":"red"}`,
+			want: Errors{"sku": "is required"},
+		},
+		{
+			name: "wrong root type",
+			doc:  `["ABC-1"]`,
+			want: Errors{"": "must be of type object"},
+		},
+		{
+			name: "unknown property is rejected",
+			doc:  `{"sku":"ABC-1","size":"xl"}`,
+			want: Errors{"size": "is not allowed"},
+		},
+		{
+			name: "pattern and enum failures",
+			doc:  `{"sku":"abc","color":"pink"}`,
+			want: Errors{
+				"sku":   "must match the pattern ^[A-Z]{3}-[0-9]+$",
+				"color": "must be one of the allowed values",
+			},
+		},
+		{
+			name: "numeric bounds and integers",
+			doc:  `{"sku":"ABC-1","weight":0,"stock":1.5}`,
+			want: Errors{
+				"weight": "must be greater than 0",
+				"stock":  "must be of type integer",
+			},
+		},
+		{
+			name: "integral floats are integers",
+			doc:  `{"sku":"ABC-1","stock":2.0}`,
+			want: Errors{},
+		},
+		{
+			name: "array items and length",
+			doc:  `{"sku":"ABC-1","labels":["ok","toolong","x"]}`,
+			want: Errors{
+				"labels":   "must have at most 2 items",
+				"labels.1": "must be at most 5 characters",
+			},
+		},
+		{
+			name: "additional properties schema",
+			doc:  `{"sku":"ABC-1","dimensions":{"w":1,"h":"tall"}}`,
+			want: Errors{"dimensions.h": "must be of type number"},
+		},
+		{
+			name: "invalid JSON",
+			doc:  `{"sku":`,
+			want: Errors{"": "must be valid JSON"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := schema.Validate([]byte(tt.doc)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRejectsInvalidSchemas(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{"not JSON", `{`},
+		{"not an object", `"object"`},
+		{"bad type", `{"type": 1}`},
+		{"bad pattern", `{"pattern": "("}`},
+		{"negative count", `{"maxLength": -1}`},
+		{"bad nested schema", `{"properties": {"a": 1}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.schema)); err == nil {
+				t.Errorf("Parse(%s) succeeded, want error", tt.schema)
+			}
+		})
+	}
+}
+
+func TestBooleanSchemas(t *testing.T) {
+	schema, err := Parse([]byte(`{"properties": {"free": true, "locked": false}}`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	got := schema.Validate([]byte(`{"free": {"any": [1, "thing"]}, "locked": 1}`))
+	want := Errors{"locked": "is not allowed"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate() = %v, want %v", got, want)
+	}
+}
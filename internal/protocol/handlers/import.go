@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.lumeweb.com/portal-plugin-template/internal/service"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"go.uber.org/zap"
+	"io"
+	"strings"
+)
+
+// ImportSource is implemented by protocols that stage item imports and track their progress
+type ImportSource interface {
+	// OpenImport opens the staged import data for a request and returns its format
+	OpenImport(ctx context.Context, req *models.Request) (io.ReadCloser, string, error)
+	// ImportRow records the outcome of a single row, err is nil if the item was created
+	ImportRow(req *models.Request, row int, err error)
+	// FinishImport marks the import as finished, err is nil if every row was processed
+	FinishImport(req *models.Request, err error)
+	// RemoveImport releases the staged import data for a request
+	RemoveImport(ctx context.Context, req *models.Request) error
+}
+
+type ImportHandler struct {
+	protocol core.Protocol
+	ctx      core.Context
+}
+
+func NewImportHandler(protocol core.Protocol, ctx core.Context) *ImportHandler {
+	return &ImportHandler{
+		protocol: protocol,
+		ctx:      ctx,
+	}
+}
+
+func (h *ImportHandler) ValidateRequest(_ context.Context, _ *models.Request) error {
+	if _, ok := h.protocol.(ImportSource); !ok {
+		return fmt.Errorf("protocol does not implement ImportSource")
+	}
+	return nil
+}
+
+// Execute creates an item for every row of the staged import. Each row is validated
+// by the item service on its own, so a bad row is reported without failing the rest
+// of the import.
+// The import stops early if an item quota is exhausted. Admins are exempt from the
+// per-user quota, as they are when creating items directly.
+func (h *ImportHandler) Execute(ctx context.Context, req *models.Request) (err error) {
+	source, ok := h.protocol.(ImportSource)
+	if !ok {
+		return fmt.Errorf("protocol does not implement ImportSource")
+	}
+	defer func() {
+		source.FinishImport(req, err)
+	}()
+
+	itemSvc := core.GetService[service.ItemService](h.ctx, service.ITEM_SERVICE)
+	logger := h.ctx.Logger()
+
+	data, format, err := source.OpenImport(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to open import: %w", err)
+	}
+	defer func(data io.ReadCloser) {
+		if err := data.Close(); err != nil {
+			logger.Error("failed to close import data", zap.Error(err))
+		}
+	}(data)
+
+	reader, err := service.NewItemReader(format, data)
+	if err != nil {
+		return err
+	}
+
+	// Rows are created with the role of the user who started the import
+	actor := service.UserActor(h.ctx, req.UserID)
+
+	for row := 1; ; row++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if errors.Is(err, service.ErrInvalidRecord) {
+			source.ImportRow(req, row, err)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read row %d: %w", row, err)
+		}
+
+		_, err = itemSvc.CreateItem(actor, strings.TrimSpace(record.Name), strings.TrimSpace(record.Description), strings.TrimSpace(record.Visibility), record.Metadata)
+		source.ImportRow(req, row, err)
+
+		var quotaErr *service.QuotaError
+		if errors.As(err, &quotaErr) {
+			return fmt.Errorf("import stopped at row %d: %w", row, err)
+		}
+	}
+}
+
+func (h *ImportHandler) GetStatus(_ context.Context, _ *models.Request) (core.RequestStatus, error) {
+	return core.RequestStatus{
+		State:   "completed",
+		Message: "Import completed",
+	}, nil
+}
+
+// Cleanup releases the staged import data once the workflow is done with it
+func (h *ImportHandler) Cleanup(ctx context.Context, req *models.Request) error {
+	source, ok := h.protocol.(ImportSource)
+	if !ok {
+		return nil
+	}
+	return source.RemoveImport(ctx, req)
+}
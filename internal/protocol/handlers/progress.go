@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"io"
+
+	"go.lumeweb.com/portal/db/models"
+)
+
+// Phases an upload moves through, in order
+const (
+	UploadPhaseReceiving = "receiving" // The data is being received
+	UploadPhaseHashing   = "hashing"   // Received data is being hashed and staged for the workflow
+	UploadPhaseStoring   = "storing"   // The workflow is storing the data
+	UploadPhaseScanning  = "scanning"  // The workflow is scanning the stored data
+	UploadPhaseDone      = "done"      // The upload finished
+	UploadPhaseFailed    = "failed"    // The upload failed, see its error
+)
+
+// UploadTracker is implemented by protocols that track the progress of uploads
+type UploadTracker interface {
+	// StartUploadPhase records that an upload entered a phase of the upload workflow.
+	// The returned function reports how many bytes the phase has processed so far.
+	StartUploadPhase(req *models.Request, phase string) func(processed uint64)
+	// FinishUpload marks an upload as done, or as failed with err if it is not nil
+	FinishUpload(req *models.Request, err error)
+}
+
+// progressReader reports the position of a ReadSeeker as it is read
+type progressReader struct {
+	io.ReadSeeker
+	position int64
+	report   func(processed uint64)
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.ReadSeeker.Read(b)
+	r.position += int64(n)
+	r.report(uint64(r.position))
+	return n, err
+}
+
+func (r *progressReader) Seek(offset int64, whence int) (int64, error) {
+	position, err := r.ReadSeeker.Seek(offset, whence)
+	if err == nil {
+		r.position = position
+	}
+	return position, err
+}
@@ -22,7 +22,15 @@ func (h *ScanHandler) ValidateRequest(_ context.Context, _ *models.Request) erro
 	return nil
 }
 
-func (h *ScanHandler) Execute(_ context.Context, _ *models.Request) error {
+func (h *ScanHandler) Execute(_ context.Context, req *models.Request) (err error) {
+	if tracker, ok := h.protocol.(UploadTracker); ok {
+		tracker.StartUploadPhase(req, UploadPhaseScanning)
+		// Scanning is the last step of the upload workflow
+		defer func() {
+			tracker.FinishUpload(req, err)
+		}()
+	}
+
 	// Implement content scanning logic
 	return nil
 }
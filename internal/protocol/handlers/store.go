@@ -4,16 +4,33 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"go.lumeweb.com/portal-plugin-template/internal"
+	pluginConfig "go.lumeweb.com/portal-plugin-template/internal/config"
 	"go.lumeweb.com/portal/core"
 	"go.lumeweb.com/portal/db/models"
 	"go.lumeweb.com/portal/service"
 	"go.uber.org/zap"
 	"io"
+	"os"
+	"path/filepath"
 )
 
+// UploadSource is implemented by protocols that keep upload data in temporary storage
+// until the upload workflow stores it
+type UploadSource interface {
+	// OpenUpload opens the temporary data of an upload
+	OpenUpload(ctx context.Context, req *models.Request) (io.ReadCloser, error)
+	// RemoveUpload deletes the temporary data of an upload
+	RemoveUpload(ctx context.Context, req *models.Request) error
+}
+
 type StoreHandler struct {
 	protocol core.Protocol
 	ctx      core.Context
+
+	// Looked up from ctx when unset
+	storage core.StorageService
+	config  *pluginConfig.Config
 }
 
 func NewStoreHandler(protocol core.Protocol, ctx core.Context) *StoreHandler {
@@ -24,44 +41,83 @@ func NewStoreHandler(protocol core.Protocol, ctx core.Context) *StoreHandler {
 }
 
 func (h *StoreHandler) ValidateRequest(_ context.Context, _ *models.Request) error {
-	// Validate request data
+	if _, ok := h.protocol.(UploadSource); !ok {
+		return fmt.Errorf("protocol does not implement UploadSource")
+	}
+	if _, ok := h.protocol.(UploadTracker); !ok {
+		return fmt.Errorf("protocol does not implement UploadTracker")
+	}
 	return nil
 }
 
-func (h *StoreHandler) Execute(ctx context.Context, req *models.Request) error {
-	// Get storage service and logger
-	storage := core.GetService[core.StorageService](h.ctx, core.STORAGE_SERVICE)
-	logger := h.ctx.Logger()
+// storageService returns the storage service objects are stored with
+func (h *StoreHandler) storageService() core.StorageService {
+	if h.storage != nil {
+		return h.storage
+	}
+	return core.GetService[core.StorageService](h.ctx, core.STORAGE_SERVICE)
+}
+
+// protocolConfig returns the plugin's protocol configuration
+func (h *StoreHandler) protocolConfig() *pluginConfig.Config {
+	if h.config != nil {
+		return h.config
+	}
+	return h.ctx.Config().GetProtocol(internal.PLUGIN_NAME).(*pluginConfig.Config)
+}
+
+// Execute stores an upload's temporary data in its final location. Uploads up to the
+// configured StoreMemoryLimit are buffered in memory, larger ones are spooled to disk, so
+// memory use stays bounded whatever the size of the upload.
+func (h *StoreHandler) Execute(ctx context.Context, req *models.Request) (err error) {
+	storage := h.storageService()
 
 	storageProtocol, ok := h.protocol.(core.StorageProtocol)
 	if !ok {
 		return fmt.Errorf("protocol does not implement StorageProtocol")
 	}
 
-	// The data is already in temporary S3 storage from the initial upload
-	// Get it from there and convert to ReadSeeker
-	readCloser, err := storage.S3GetTemporaryUpload(ctx, storageProtocol, fmt.Sprintf("%d", req.ID))
+	source, ok := h.protocol.(UploadSource)
+	if !ok {
+		return fmt.Errorf("protocol does not implement UploadSource")
+	}
+
+	tracker, ok := h.protocol.(UploadTracker)
+	if !ok {
+		return fmt.Errorf("protocol does not implement UploadTracker")
+	}
+	report := tracker.StartUploadPhase(req, UploadPhaseStoring)
+	defer func() {
+		// A failed store fails the workflow, so the upload ends here
+		if err != nil {
+			tracker.FinishUpload(req, err)
+		}
+	}()
+
+	// The data was committed to temporary storage before the workflow started
+	readCloser, err := source.OpenUpload(ctx, req)
 	if err != nil {
 		return fmt.Errorf("failed to get temporary upload: %w", err)
 	}
 	defer func(readCloser io.ReadCloser) {
 		err := readCloser.Close()
 		if err != nil {
-			logger.Error("failed to close temporary upload reader", zap.Error(err))
+			h.ctx.Logger().Error("failed to close temporary upload reader", zap.Error(err))
 		}
 	}(readCloser)
 
-	// Read all data into memory to create a ReadSeeker
-	data, err := io.ReadAll(readCloser)
+	// The storage service needs a ReadSeeker, spool large uploads to disk rather than memory if the data can't seek
+	cfg := h.protocolConfig()
+	reader, release, err := seekableUpload(readCloser, req.Size, cfg.StoreMemoryLimit, filepath.Join(cfg.StoragePath, "spool"))
 	if err != nil {
-		return fmt.Errorf("failed to read upload  %w", err)
+		return fmt.Errorf("failed to spool upload: %w", err)
 	}
-	reader := bytes.NewReader(data)
+	defer release()
 
 	// Create upload request for final storage
 	uploadReq := service.NewStorageUploadRequest(
 		core.StorageUploadWithProtocol(storageProtocol),
-		core.StorageUploadWithData(reader),
+		core.StorageUploadWithData(&progressReader{ReadSeeker: reader, report: report}),
 		core.StorageUploadWithSize(req.Size),
 		core.StorageUploadWithProof(core.NewStorageHashFromMultihashBytes(req.Hash, 0, nil)),
 	)
@@ -72,12 +128,6 @@ func (h *StoreHandler) Execute(ctx context.Context, req *models.Request) error {
 		return fmt.Errorf("failed to store object: %w", err)
 	}
 
-	// Cleanup temporary upload
-	if err := storage.S3DeleteTemporaryUpload(ctx, storageProtocol, fmt.Sprintf("%d", req.ID)); err != nil {
-		// Log but don't fail if cleanup fails
-		logger.Error("failed to cleanup temporary upload", zap.Error(err))
-	}
-
 	return nil
 }
 
@@ -88,6 +138,54 @@ func (h *StoreHandler) GetStatus(_ context.Context, _ *models.Request) (core.Req
 	}, nil
 }
 
-func (h *StoreHandler) Cleanup(_ context.Context, _ *models.Request) error {
-	return nil
+// Cleanup removes the temporary upload data once the workflow is done with it
+func (h *StoreHandler) Cleanup(ctx context.Context, req *models.Request) error {
+	source, ok := h.protocol.(UploadSource)
+	if !ok {
+		return nil
+	}
+	return source.RemoveUpload(ctx, req)
+}
+
+// seekableUpload returns size bytes of data as an io.ReadSeeker, buffering at most memoryLimit
+// bytes in memory. Data that can already seek is used as is, data within the limit is read
+// into memory and anything else is spooled to a temporary file under dir. The returned
+// function removes the spool file.
+func seekableUpload(data io.Reader, size uint64, memoryLimit int64, dir string) (io.ReadSeeker, func(), error) {
+	if seeker, ok := data.(io.ReadSeeker); ok {
+		return seeker, func() {}, nil
+	}
+
+	if memoryLimit > 0 && size <= uint64(memoryLimit) {
+		buf := make([]byte, size)
+		if _, err := io.ReadFull(data, buf); err != nil {
+			return nil, nil, err
+		}
+		return bytes.NewReader(buf), func() {}, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.CreateTemp(dir, "store-*.tmp")
+	if err != nil {
+		return nil, nil, err
+	}
+	release := func() {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+	}
+
+	if _, err := io.Copy(file, data); err != nil {
+		release()
+		return nil, nil, err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		release()
+		return nil, nil, err
+	}
+
+	return file, release, nil
 }
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	pluginConfig "go.lumeweb.com/portal-plugin-template/internal/config"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+)
+
+const (
+	// testMemoryLimit is the StoreMemoryLimit configured for test uploads
+	testMemoryLimit = 4 << 20
+
+	// testUploadSize is the size of the large test upload, much larger than testMemoryLimit
+	testUploadSize = 64 << 20
+)
+
+// streamOnly hides any Seek method, so uploads have to be buffered or spooled
+type streamOnly struct {
+	io.Reader
+}
+
+// storeTestProtocol serves a generated upload as its temporary data and records the
+// phases reported for it
+type storeTestProtocol struct {
+	core.Protocol
+
+	data     io.Reader
+	finished error
+	reported uint64
+}
+
+func (p *storeTestProtocol) EncodeFileName(hash core.StorageHash) string {
+	return hash.Multihash().B58String()
+}
+
+func (p *storeTestProtocol) Hash(r io.Reader, _ uint64) (core.StorageHash, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return core.NewStorageHash(h.Sum(nil), uint64(sha256.Size), 0, nil), nil
+}
+
+func (p *storeTestProtocol) HandleUpload(context.Context, io.Reader, uint64) (core.StorageHash, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *storeTestProtocol) OpenUpload(context.Context, *models.Request) (io.ReadCloser, error) {
+	return io.NopCloser(streamOnly{p.data}), nil
+}
+
+func (p *storeTestProtocol) RemoveUpload(context.Context, *models.Request) error {
+	return nil
+}
+
+func (p *storeTestProtocol) StartUploadPhase(_ *models.Request, _ string) func(processed uint64) {
+	return func(processed uint64) {
+		p.reported = processed
+	}
+}
+
+func (p *storeTestProtocol) FinishUpload(_ *models.Request, err error) {
+	p.finished = err
+}
+
+// storeTestStorage consumes stored objects the way the storage service does, reading the
+// data a second time after seeking back, and hashes what it stored
+type storeTestStorage struct {
+	core.StorageService
+
+	stored hash.Hash
+	size   int64
+}
+
+func (s *storeTestStorage) UploadObject(_ context.Context, req core.StorageUploadRequest) (*models.Request, error) {
+	data := req.Data()
+	if _, err := io.Copy(io.Discard, data); err != nil {
+		return nil, err
+	}
+	if _, err := data.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	s.stored = sha256.New()
+	size, err := io.Copy(s.stored, data)
+	s.size = size
+	return nil, err
+}
+
+// newTestStoreHandler creates a StoreHandler storing size bytes of generated data, returning
+// the hash of the data
+func newTestStoreHandler(t *testing.T, size int64) (*StoreHandler, *storeTestProtocol, *storeTestStorage, []byte) {
+	t.Helper()
+
+	want := sha256.New()
+	_, _ = io.Copy(want, io.LimitReader(rand.New(rand.NewSource(1)), size))
+
+	proto := &storeTestProtocol{data: io.LimitReader(rand.New(rand.NewSource(1)), size)}
+	storage := &storeTestStorage{}
+	handler := &StoreHandler{
+		protocol: proto,
+		storage:  storage,
+		config:   &pluginConfig.Config{StoragePath: t.TempDir(), StoreMemoryLimit: testMemoryLimit},
+	}
+
+	return handler, proto, storage, want.Sum(nil)
+}
+
+// executeStore runs the handler for an upload of size bytes and checks what was stored
+func executeStore(t *testing.T, handler *StoreHandler, proto *storeTestProtocol, storage *storeTestStorage, size int64, want []byte) {
+	t.Helper()
+
+	req := &models.Request{Size: uint64(size)}
+	if err := handler.Execute(context.Background(), req); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if proto.finished != nil {
+		t.Errorf("Execute() finished the upload with %v", proto.finished)
+	}
+	if storage.size != size {
+		t.Errorf("stored %d bytes, want %d", storage.size, size)
+	}
+	if !bytes.Equal(storage.stored.Sum(nil), want) {
+		t.Error("stored object differs from the uploaded data")
+	}
+	if proto.reported == 0 {
+		t.Error("Execute() reported no storing progress")
+	}
+}
+
+func TestStoreHandlerBoundedMemory(t *testing.T) {
+	handler, proto, storage, want := newTestStoreHandler(t, testUploadSize)
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	executeStore(t, handler, proto, storage, testUploadSize, want)
+
+	runtime.ReadMemStats(&after)
+
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > uint64(handler.config.StoreMemoryLimit) {
+		t.Errorf("storing a %d byte upload allocated %d bytes, want at most the %d byte memory limit",
+			testUploadSize, allocated, handler.config.StoreMemoryLimit)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(handler.config.StoragePath, "spool"))
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("spool directory holds %d files after the upload was stored, want none", len(entries))
+	}
+}
+
+func TestStoreHandlerBuffersSmallUploads(t *testing.T) {
+	const size = 64 << 10
+	handler, proto, storage, want := newTestStoreHandler(t, size)
+
+	executeStore(t, handler, proto, storage, size, want)
+
+	if _, err := os.Stat(filepath.Join(handler.config.StoragePath, "spool")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("upload within the memory limit was spooled to disk: %v", err)
+	}
+}
+
+func TestSeekableUploadUsesSeeker(t *testing.T) {
+	dir := t.TempDir()
+	data := strings.NewReader("already seekable")
+
+	reader, release, err := seekableUpload(data, uint64(data.Len()), 0, dir)
+	if err != nil {
+		t.Fatalf("seekableUpload() error = %v", err)
+	}
+	defer release()
+
+	if reader != io.ReadSeeker(data) {
+		t.Error("seekableUpload() spooled data that can already seek")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("spool directory holds %d files, want none", len(entries))
+	}
+}
@@ -0,0 +1,402 @@
+package protocol
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	pluginModels "go.lumeweb.com/portal-plugin-template/internal/db/models"
+	"go.lumeweb.com/portal-plugin-template/internal/protocol/handlers"
+	"go.lumeweb.com/portal-plugin-template/internal/protocol/workflow"
+	"go.lumeweb.com/portal-plugin-template/internal/service"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ handlers.ImportSource = (*Protocol)(nil)
+
+// maxImportErrors is the number of row errors kept for each import
+const maxImportErrors = 100
+
+// importRowError describes why a single import row was not imported
+type importRowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+// importState tracks the progress of an item import
+// States are kept in memory by the node running the import and persisted as
+// ImportProgress rows, like the progress of uploads.
+type importState struct {
+	ID        string
+	UserID    uint
+	Format    string
+	Status    string
+	Rows      int
+	Created   int
+	Failed    int
+	Errors    []importRowError
+	Error     string
+	Started   time.Time
+	Updated   time.Time
+	Completed bool
+
+	dirty bool // Whether the row counts changed since the state was persisted
+}
+
+// importKey returns the temporary storage key of an import with the given content hash and format
+func importKey(hash core.StorageHash, format string) string {
+	return uploadKey(hash) + "." + format
+}
+
+// StartImport stages an item import in temporary storage and starts the import workflow for it.
+// Returns the import ID used to query its progress.
+func (p *Protocol) StartImport(ctx context.Context, userID uint, format string, data io.Reader) (string, error) {
+	if !p.isRunning {
+		return "", errors.New("protocol not running")
+	}
+
+	if !service.ValidTransferFormat(format) {
+		return "", fmt.Errorf("%w: unsupported import format %q", service.ErrValidation, format)
+	}
+
+	// Temporary storage needs the size up front, which import bodies don't have to declare,
+	// so the body is spooled to a local file first
+	dir := filepath.Join(p.config.StoragePath, "spool")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create spool directory: %w", err)
+	}
+	spool, err := os.CreateTemp(dir, "import-*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create import file: %w", err)
+	}
+	defer func() {
+		_ = spool.Close()
+		_ = os.Remove(spool.Name())
+	}()
+
+	size, err := io.Copy(spool, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to spool import: %w", err)
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to spool import: %w", err)
+	}
+
+	hash, staging, err := stageUpload(ctx, p.uploadStorage, spool, uint64(size), io.Discard)
+	if err != nil {
+		return "", err
+	}
+
+	key := importKey(hash, format)
+	if err := p.commitData(ctx, staging, key); err != nil {
+		return "", err
+	}
+
+	req := &models.Request{
+		Protocol: p.Name(),
+		Hash:     hash.Multihash(),
+		Size:     uint64(size),
+		UserID:   userID,
+	}
+
+	if _, err := p.coordinator.StartWorkflow(ctx, workflow.WorkflowImport, req); err != nil {
+		_ = p.releaseUpload(ctx, key)
+		return "", fmt.Errorf("failed to start import workflow: %w", err)
+	}
+
+	// The workflow may already have recorded the import on another node
+	progress := pluginModels.ImportProgress{
+		ID:     strconv.FormatUint(uint64(req.ID), 10),
+		UserID: userID,
+		Format: format,
+	}
+	if err := p.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&progress).Error; err != nil {
+		p.logger.Error("failed to record import progress", zap.String("import_id", progress.ID), zap.Error(err))
+	}
+
+	return progress.ID, nil
+}
+
+// importState returns the tracked state of an import, loading it if the node starting
+// the import recorded it, or creating it if the workflow reached it first
+func (p *Protocol) importState(req *models.Request) *importState {
+	id := strconv.FormatUint(uint64(req.ID), 10)
+
+	p.importsMu.RLock()
+	state, ok := p.imports[id]
+	p.importsMu.RUnlock()
+	if ok {
+		return state
+	}
+
+	state, err := p.loadImport(id)
+	if err != nil {
+		p.logger.Error("failed to load import progress", zap.String("import_id", id), zap.Error(err))
+	}
+	if state == nil {
+		state = &importState{
+			ID:      id,
+			UserID:  req.UserID,
+			Started: time.Now(),
+		}
+	}
+	state.Updated = time.Now()
+
+	p.importsMu.Lock()
+	defer p.importsMu.Unlock()
+
+	if existing, ok := p.imports[id]; ok {
+		return existing
+	}
+	p.imports[id] = state
+
+	return state
+}
+
+// OpenImport opens the temporary data of an import and returns its format
+func (p *Protocol) OpenImport(ctx context.Context, req *models.Request) (io.ReadCloser, string, error) {
+	hash := core.NewStorageHashFromMultihashBytes(req.Hash, 0, nil)
+
+	for _, format := range service.TransferFormats() {
+		data, err := p.uploadData(importKey(hash, format))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+
+		reader, err := p.uploadStorage.Open(ctx, data.StorageKey)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to open import data: %w", err)
+		}
+
+		state := p.importState(req)
+		p.importsMu.Lock()
+		state.Format = format
+		p.importsMu.Unlock()
+		p.saveImport(state)
+
+		return reader, format, nil
+	}
+
+	return nil, "", fmt.Errorf("import data for request %d not found", req.ID)
+}
+
+// ImportRow records the outcome of a single import row
+func (p *Protocol) ImportRow(req *models.Request, row int, err error) {
+	state := p.importState(req)
+
+	p.importsMu.Lock()
+	defer p.importsMu.Unlock()
+
+	state.Rows++
+	state.dirty = true
+	if err == nil {
+		state.Created++
+		return
+	}
+
+	state.Failed++
+	if len(state.Errors) < maxImportErrors {
+		state.Errors = append(state.Errors, importRowError{Row: row, Message: err.Error()})
+	}
+}
+
+// FinishImport marks an import as finished, recording why it stopped early if it did.
+// The import is persisted and dropped from memory.
+func (p *Protocol) FinishImport(req *models.Request, err error) {
+	state := p.importState(req)
+
+	p.importsMu.Lock()
+	state.Completed = true
+	if err != nil {
+		state.Error = err.Error()
+	}
+	p.importsMu.Unlock()
+
+	p.saveImport(state)
+	p.untrackImport(state)
+}
+
+// RemoveImport releases the temporary data of an import, deleting it once no other import uses it
+func (p *Protocol) RemoveImport(ctx context.Context, req *models.Request) error {
+	hash := core.NewStorageHashFromMultihashBytes(req.Hash, 0, nil)
+
+	for _, format := range service.TransferFormats() {
+		key := importKey(hash, format)
+
+		var records int64
+		if err := p.db.Model(&pluginModels.UploadData{}).Where("hash = ?", key).Count(&records).Error; err != nil {
+			return fmt.Errorf("failed to find import data: %w", err)
+		}
+		if records > 0 {
+			return p.releaseUpload(ctx, key)
+		}
+	}
+
+	return nil
+}
+
+// untrackImport drops an import from memory once this node no longer updates it
+func (p *Protocol) untrackImport(state *importState) {
+	p.importsMu.Lock()
+	defer p.importsMu.Unlock()
+
+	if p.imports[state.ID] == state {
+		delete(p.imports, state.ID)
+	}
+}
+
+// saveImport persists the progress of an import
+func (p *Protocol) saveImport(state *importState) {
+	p.importsMu.Lock()
+	state.dirty = false
+	state.Updated = time.Now()
+
+	progress := pluginModels.ImportProgress{
+		ID:        state.ID,
+		UserID:    state.UserID,
+		Format:    state.Format,
+		Rows:      state.Rows,
+		Created:   state.Created,
+		Failed:    state.Failed,
+		Error:     state.Error,
+		Completed: state.Completed,
+		CreatedAt: state.Started,
+		UpdatedAt: state.Updated,
+	}
+	rowErrors, err := json.Marshal(state.Errors)
+	p.importsMu.Unlock()
+
+	if err != nil {
+		p.logger.Error("failed to encode import errors", zap.String("import_id", progress.ID), zap.Error(err))
+	}
+	progress.Errors = rowErrors
+
+	if err := p.db.Save(&progress).Error; err != nil {
+		p.logger.Error("failed to save import progress", zap.String("import_id", progress.ID), zap.Error(err))
+	}
+}
+
+// loadImport loads the persisted progress of an import, nil if none was recorded
+func (p *Protocol) loadImport(importID string) (*importState, error) {
+	var progress pluginModels.ImportProgress
+	err := p.db.Where("id = ?", importID).First(&progress).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := &importState{
+		ID:        progress.ID,
+		UserID:    progress.UserID,
+		Format:    progress.Format,
+		Rows:      progress.Rows,
+		Created:   progress.Created,
+		Failed:    progress.Failed,
+		Error:     progress.Error,
+		Started:   progress.CreatedAt,
+		Updated:   progress.UpdatedAt,
+		Completed: progress.Completed,
+	}
+	if len(progress.Errors) > 0 {
+		if err := json.Unmarshal(progress.Errors, &state.Errors); err != nil {
+			return nil, fmt.Errorf("invalid import errors: %w", err)
+		}
+	}
+
+	return state, nil
+}
+
+// importProgress returns a snapshot of the progress of an import, nil if none was recorded.
+// The state kept in memory by the node running the import has fresher row counts than
+// the persisted one.
+func (p *Protocol) importProgress(importID string) (*importState, error) {
+	p.importsMu.RLock()
+	local, ok := p.imports[importID]
+	var snapshot importState
+	if ok {
+		snapshot = *local
+		snapshot.Errors = append([]importRowError(nil), local.Errors...)
+	}
+	p.importsMu.RUnlock()
+
+	if ok {
+		return &snapshot, nil
+	}
+
+	return p.loadImport(importID)
+}
+
+// flushImports persists changed row counts and expires idle and old imports
+func (p *Protocol) flushImports(now time.Time) {
+	var dirty, idle []*importState
+	p.importsMu.RLock()
+	for _, state := range p.imports {
+		switch {
+		case state.dirty:
+			dirty = append(dirty, state)
+		case now.Sub(state.Updated) > progressIdleTimeout:
+			idle = append(idle, state)
+		}
+	}
+	p.importsMu.RUnlock()
+
+	for _, state := range dirty {
+		p.saveImport(state)
+	}
+	for _, state := range idle {
+		p.untrackImport(state)
+	}
+
+	if err := p.db.Where("updated_at < ?", now.Add(-progressRetention)).Delete(&pluginModels.ImportProgress{}).Error; err != nil {
+		p.logger.Error("failed to expire import progress", zap.Error(err))
+	}
+}
+
+// GetImportStatus gets the progress of an import along with its workflow state
+func (p *Protocol) GetImportStatus(importID string) (*importState, error) {
+	requestID, err := strconv.ParseUint(importID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid import ID: %w", err)
+	}
+
+	status, err := p.coordinator.GetWorkflowStatus(context.Background(), uint(requestID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow status: %w", err)
+	}
+
+	state, err := p.importProgress(importID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load import progress: %w", err)
+	}
+
+	if state == nil {
+		// Imports without recorded progress only report their workflow state
+		requestSvc := p.ctx.Service(core.REQUEST_SERVICE).(core.RequestService)
+		req, err := requestSvc.GetRequest(context.Background(), uint(requestID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get request: %w", err)
+		}
+		state = &importState{ID: importID, UserID: req.UserID}
+	}
+
+	state.Status = status.Status
+	state.Started = status.StartedAt
+	state.Completed = state.Completed || status.Status == string(models.RequestStatusCompleted)
+
+	return state, nil
+}
@@ -0,0 +1,169 @@
+package protocol
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	pluginModels "go.lumeweb.com/portal-plugin-template/internal/db/models"
+	"go.lumeweb.com/portal-plugin-template/internal/service"
+	"go.lumeweb.com/portal/db/models"
+	"gorm.io/gorm"
+)
+
+// newTestImportNode creates a Protocol tracking imports in db, standing in for a portal node
+func newTestImportNode(db *gorm.DB, storage TemporaryStorage) *Protocol {
+	p := newTestNode(db)
+	p.uploadStorage = storage
+	p.imports = make(map[string]*importState)
+	return p
+}
+
+// stageTestImport stages import data on p as StartImport does, returning its workflow request
+func stageTestImport(t *testing.T, p *Protocol, id uint, format string, data string) *models.Request {
+	t.Helper()
+	ctx := context.Background()
+
+	hash, staging, err := stageUpload(ctx, p.uploadStorage, strings.NewReader(data), uint64(len(data)), io.Discard)
+	if err != nil {
+		t.Fatalf("stageUpload() error = %v", err)
+	}
+	if err := p.commitData(ctx, staging, importKey(hash, format)); err != nil {
+		t.Fatalf("commitData() error = %v", err)
+	}
+
+	req := &models.Request{Hash: hash.Multihash(), Size: uint64(len(data)), UserID: 7}
+	req.ID = id
+	return req
+}
+
+func TestImportDataAcrossNodes(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	storage := newMemoryStorage()
+	first, second := newTestImportNode(db, storage), newTestImportNode(db, storage)
+
+	data := "name\nimported item\n"
+	req := stageTestImport(t, first, 42, service.FormatCSV, data)
+	duplicate := stageTestImport(t, second, 43, service.FormatCSV, data)
+
+	if len(storage.objects) != 1 {
+		t.Fatalf("storage holds %d copies of identical imports, want 1", len(storage.objects))
+	}
+
+	// The workflow can run on a node other than the one staging the import
+	reader, format, err := second.OpenImport(ctx, req)
+	if err != nil {
+		t.Fatalf("OpenImport() error = %v", err)
+	}
+	stored, _ := io.ReadAll(reader)
+	if string(stored) != data || format != service.FormatCSV {
+		t.Errorf("OpenImport() = %q, %q, want %q, csv", stored, format, data)
+	}
+
+	if err := second.RemoveImport(ctx, req); err != nil {
+		t.Fatalf("RemoveImport() error = %v", err)
+	}
+	if len(storage.objects) != 1 {
+		t.Fatal("data removed while another import still uses it")
+	}
+
+	// A restarted node releases the remaining reference
+	restarted := newTestImportNode(db, storage)
+	if err := restarted.RemoveImport(ctx, duplicate); err != nil {
+		t.Fatalf("RemoveImport() error = %v", err)
+	}
+	if len(storage.objects) != 0 {
+		t.Errorf("storage holds %d objects after the last import was removed, want none", len(storage.objects))
+	}
+
+	if _, _, err := restarted.OpenImport(ctx, req); err == nil {
+		t.Error("OpenImport() opened removed import data")
+	}
+}
+
+func TestImportProgressAcrossNodes(t *testing.T) {
+	db := newTestDB(t)
+	storage := newMemoryStorage()
+	first, second := newTestImportNode(db, storage), newTestImportNode(db, storage)
+	req := stageTestImport(t, first, 44, service.FormatNDJSON, "{\"name\":\"imported item\"}\n")
+
+	reader, _, err := first.OpenImport(context.Background(), req)
+	if err != nil {
+		t.Fatalf("OpenImport() error = %v", err)
+	}
+	_ = reader.Close()
+
+	first.ImportRow(req, 1, nil)
+	first.ImportRow(req, 2, errors.New("name is required"))
+	first.flushProgress()
+
+	state, err := second.importProgress("44")
+	if err != nil {
+		t.Fatalf("importProgress() error = %v", err)
+	}
+	if state == nil {
+		t.Fatal("importProgress() found no progress recorded by another node")
+	}
+	if state.Rows != 2 || state.Created != 1 || state.Failed != 1 || state.Format != service.FormatNDJSON || state.UserID != 7 {
+		t.Errorf("importProgress() = rows %d, created %d, failed %d, format %q, user %d, want 2, 1, 1, ndjson, 7",
+			state.Rows, state.Created, state.Failed, state.Format, state.UserID)
+	}
+	if len(state.Errors) != 1 || state.Errors[0].Row != 2 || state.Errors[0].Message != "name is required" {
+		t.Errorf("importProgress() errors = %+v, want row 2 rejected", state.Errors)
+	}
+
+	first.FinishImport(req, nil)
+
+	if len(first.imports) != 0 {
+		t.Error("finished import kept in memory")
+	}
+	state, err = second.importProgress("44")
+	if err != nil {
+		t.Fatalf("importProgress() error = %v", err)
+	}
+	if !state.Completed || state.Rows != 2 {
+		t.Errorf("importProgress() = completed %v, rows %d, want true, 2", state.Completed, state.Rows)
+	}
+}
+
+func TestFlushImportsExpiry(t *testing.T) {
+	db := newTestDB(t)
+	p := newTestImportNode(db, newMemoryStorage())
+	now := time.Now()
+
+	active := &importState{ID: "active", Started: now, Updated: now}
+	idle := &importState{ID: "idle", Started: now, Updated: now.Add(-progressIdleTimeout - time.Minute)}
+	p.imports[active.ID] = active
+	p.imports[idle.ID] = idle
+	p.saveImport(active)
+
+	old := pluginModels.ImportProgress{
+		ID:        "old",
+		Format:    service.FormatCSV,
+		Completed: true,
+		CreatedAt: now.Add(-progressRetention - time.Hour),
+		UpdatedAt: now.Add(-progressRetention - time.Hour),
+	}
+	if err := db.Create(&old).Error; err != nil {
+		t.Fatalf("failed to record old progress: %v", err)
+	}
+
+	p.flushImports(now)
+
+	if _, ok := p.imports["idle"]; ok {
+		t.Error("idle import kept in memory")
+	}
+	if _, ok := p.imports["active"]; !ok {
+		t.Error("active import dropped from memory")
+	}
+
+	var ids []string
+	db.Model(&pluginModels.ImportProgress{}).Order("id").Pluck("id", &ids)
+	if len(ids) != 1 || ids[0] != "active" {
+		t.Errorf("persisted progress = %v, want [active] after the old import expired", ids)
+	}
+}
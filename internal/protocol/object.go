@@ -0,0 +1,149 @@
+package protocol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/multiformats/go-multihash"
+	"go.lumeweb.com/portal/core"
+)
+
+// ErrInvalidFileName is returned when a file name is not a base58 encoded multihash
+var ErrInvalidFileName = errors.New("invalid object hash")
+
+// DecodeFileName parses a file name produced by EncodeFileName back into a storage hash
+func (p *Protocol) DecodeFileName(name string) (core.StorageHash, error) {
+	mh, err := multihash.FromB58String(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFileName, err)
+	}
+
+	return core.NewStorageHashFromMultihashBytes(mh, 0, nil), nil
+}
+
+// Object is a stored object opened for reading
+type Object interface {
+	io.ReadSeekCloser
+
+	// Size returns the size of the object in bytes
+	Size() uint64
+
+	// ModTime returns when the object was uploaded
+	ModTime() time.Time
+
+	// MimeType returns the detected content type of the object, if any
+	MimeType() string
+
+	// UploaderID returns the ID of the user who uploaded the object
+	UploaderID() uint
+}
+
+// object implements Object on top of the storage service
+// Data is only requested from the storage service once it is read, and seeking
+// reopens the object at the new offset, so byte ranges are served without
+// downloading the whole object.
+type object struct {
+	hash       core.StorageHash
+	size       uint64
+	mimeType   string
+	uploaderID uint
+	created    time.Time
+
+	ctx    context.Context
+	proto  *Protocol
+	offset int64
+	body   io.ReadCloser
+}
+
+// OpenObject looks up a stored object by its hash and opens it for reading
+func (p *Protocol) OpenObject(ctx context.Context, hash core.StorageHash) (Object, error) {
+	uploadSvc := core.GetService[core.UploadService](p.ctx, core.UPLOAD_SERVICE)
+
+	meta, err := uploadSvc.GetUploadMetadata(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object metadata: %w", err)
+	}
+
+	return &object{
+		hash:       hash,
+		size:       meta.Size,
+		mimeType:   meta.MimeType,
+		uploaderID: meta.UserID,
+		created:    meta.Created,
+		ctx:        ctx,
+		proto:      p,
+	}, nil
+}
+
+func (o *object) Size() uint64 {
+	return o.size
+}
+
+func (o *object) ModTime() time.Time {
+	return o.created
+}
+
+func (o *object) MimeType() string {
+	return o.mimeType
+}
+
+func (o *object) UploaderID() uint {
+	return o.uploaderID
+}
+
+func (o *object) Read(b []byte) (int, error) {
+	if o.offset >= int64(o.size) {
+		return 0, io.EOF
+	}
+
+	if o.body == nil {
+		body, err := o.proto.storage.DownloadObject(o.ctx, o.proto, o.hash, o.offset)
+		if err != nil {
+			return 0, fmt.Errorf("failed to download object: %w", err)
+		}
+		o.body = body
+	}
+
+	n, err := o.body.Read(b)
+	o.offset += int64(n)
+	return n, err
+}
+
+func (o *object) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += o.offset
+	case io.SeekEnd:
+		offset += int64(o.size)
+	default:
+		return 0, errors.New("invalid whence")
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	if offset != o.offset {
+		if err := o.Close(); err != nil {
+			return 0, err
+		}
+		o.offset = offset
+	}
+
+	return offset, nil
+}
+
+// Close releases the current download, if any. The object can still be read afterwards.
+func (o *object) Close() error {
+	if o.body == nil {
+		return nil
+	}
+
+	err := o.body.Close()
+	o.body = nil
+	return err
+}
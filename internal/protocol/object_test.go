@@ -0,0 +1,48 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/multiformats/go-multihash"
+	"go.lumeweb.com/portal/core"
+)
+
+func TestDecodeFileName(t *testing.T) {
+	p := &Protocol{}
+
+	mh, err := multihash.Sum([]byte("template object"), multihash.SHA2_256, -1)
+	if err != nil {
+		t.Fatalf("multihash.Sum() error = %v", err)
+	}
+
+	name := p.EncodeFileName(core.NewStorageHashFromMultihashBytes(mh, 0, nil))
+	hash, err := p.DecodeFileName(name)
+	if err != nil {
+		t.Fatalf("DecodeFileName(%q) error = %v", name, err)
+	}
+	if !bytes.Equal(hash.Multihash(), mh) {
+		t.Errorf("DecodeFileName(%q) = %x, want %x", name, hash.Multihash(), []byte(mh))
+	}
+
+	tests := []struct {
+		name     string
+		fileName string
+	}{
+		{name: "empty", fileName: ""},
+		{name: "not base58", fileName: "0OIl"},
+		{name: "too short", fileName: "1"},
+		{name: "truncated digest", fileName: multihash.Multihash(mh[:len(mh)-1]).B58String()},
+		{name: "trailing bytes", fileName: multihash.Multihash(append(append([]byte{}, mh...), 0)).B58String()},
+		{name: "leading zero bytes", fileName: "11111"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := p.DecodeFileName(tt.fileName); !errors.Is(err, ErrInvalidFileName) {
+				t.Errorf("DecodeFileName(%q) error = %v, want ErrInvalidFileName", tt.fileName, err)
+			}
+		})
+	}
+}
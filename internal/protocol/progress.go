@@ -0,0 +1,417 @@
+package protocol
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	pluginModels "go.lumeweb.com/portal-plugin-template/internal/db/models"
+	"go.lumeweb.com/portal-plugin-template/internal/protocol/handlers"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// progressFlushInterval is how often changed byte counters are persisted
+	progressFlushInterval = 2 * time.Second
+
+	// progressIdleTimeout is how long an upload may go without updates before it is dropped
+	// from memory, as another node has taken it over or its client has gone away
+	progressIdleTimeout = 10 * time.Minute
+
+	// progressRetention is how long the progress of an upload is kept after its last update
+	progressRetention = 24 * time.Hour
+
+	// progressWatchInterval is how often watched uploads are checked for byte progress
+	// and for changes made by other nodes
+	progressWatchInterval = time.Second
+
+	// uploadStatusProcessing is the workflow status derived for uploads still being stored
+	uploadStatusProcessing = "processing"
+)
+
+var (
+	_ handlers.UploadTracker = (*Protocol)(nil)
+	_ handlers.UploadSource  = (*Protocol)(nil)
+)
+
+// uploadFinished reports whether an upload in the given phase has finished
+func uploadFinished(phase string) bool {
+	return phase == handlers.UploadPhaseDone || phase == handlers.UploadPhaseFailed
+}
+
+// progressWriter counts the bytes written to it as processed by the current phase of an upload
+type progressWriter struct {
+	p     *Protocol
+	state *UploadState
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	w.p.uploadsMu.Lock()
+	defer w.p.uploadsMu.Unlock()
+
+	w.state.Processed += uint64(len(b))
+	if w.state.Phase == handlers.UploadPhaseReceiving {
+		w.state.Uploaded = w.state.Processed
+	}
+	w.state.dirty = true
+
+	return len(b), nil
+}
+
+// trackUpload starts tracking the progress of an upload and persists it
+func (p *Protocol) trackUpload(state *UploadState) {
+	p.uploadsMu.Lock()
+	p.uploads[state.ID] = state
+	if state.RequestID != 0 {
+		p.uploadRequests[state.RequestID] = state
+	}
+	p.uploadsMu.Unlock()
+
+	p.saveProgress(state)
+}
+
+// untrackUpload drops an upload from memory once this node no longer updates it
+func (p *Protocol) untrackUpload(state *UploadState) {
+	p.uploadsMu.Lock()
+	defer p.uploadsMu.Unlock()
+
+	if p.uploads[state.ID] == state {
+		delete(p.uploads, state.ID)
+	}
+	if p.uploadRequests[state.RequestID] == state {
+		delete(p.uploadRequests, state.RequestID)
+	}
+}
+
+// requestUpload returns the upload state of a workflow request.
+// The state is loaded if another node recorded it, or created if the workflow
+// reached the request before the node starting it recorded it.
+func (p *Protocol) requestUpload(req *models.Request) *UploadState {
+	p.uploadsMu.RLock()
+	state, ok := p.uploadRequests[req.ID]
+	p.uploadsMu.RUnlock()
+	if ok {
+		return state
+	}
+
+	id := strconv.FormatUint(uint64(req.ID), 10)
+
+	state, err := p.loadProgress(id)
+	if err != nil {
+		p.logger.Error("failed to load upload progress", zap.String("upload_id", id), zap.Error(err))
+	}
+	if state == nil {
+		state = &UploadState{
+			ID:       id,
+			UserID:   req.UserID,
+			Size:     req.Size,
+			Uploaded: req.Size,
+			Started:  time.Now(),
+			Hash:     core.NewStorageHashFromMultihashBytes(req.Hash, 0, nil),
+		}
+	}
+	state.RequestID = req.ID
+	state.Updated = time.Now()
+
+	p.uploadsMu.Lock()
+	defer p.uploadsMu.Unlock()
+
+	if existing, ok := p.uploadRequests[req.ID]; ok {
+		return existing
+	}
+	p.uploads[id] = state
+	p.uploadRequests[req.ID] = state
+
+	return state
+}
+
+// setUploadPhase moves an upload to a phase, recording err if it is not nil.
+// Phase changes are persisted right away so every node sees them, and finished
+// uploads are dropped from memory.
+func (p *Protocol) setUploadPhase(state *UploadState, phase string, err error) {
+	p.uploadsMu.Lock()
+	state.Phase = phase
+	state.Processed = 0
+	state.Completed = phase == handlers.UploadPhaseDone
+	if err != nil {
+		state.Error = err.Error()
+	}
+	close(p.uploadsChanged)
+	p.uploadsChanged = make(chan struct{})
+	p.uploadsMu.Unlock()
+
+	p.saveProgress(state)
+	if uploadFinished(phase) {
+		p.untrackUpload(state)
+	}
+}
+
+// StartUploadPhase records that an upload entered a phase of the upload workflow
+func (p *Protocol) StartUploadPhase(req *models.Request, phase string) func(processed uint64) {
+	state := p.requestUpload(req)
+	p.setUploadPhase(state, phase, nil)
+
+	return func(processed uint64) {
+		p.uploadsMu.Lock()
+		defer p.uploadsMu.Unlock()
+
+		state.Processed = processed
+		state.dirty = true
+	}
+}
+
+// FinishUpload marks an upload as done, or as failed with err if it is not nil
+func (p *Protocol) FinishUpload(req *models.Request, err error) {
+	phase := handlers.UploadPhaseDone
+	if err != nil {
+		phase = handlers.UploadPhaseFailed
+	}
+
+	p.setUploadPhase(p.requestUpload(req), phase, err)
+}
+
+// saveProgress persists the progress of an upload
+func (p *Protocol) saveProgress(state *UploadState) {
+	p.uploadsMu.Lock()
+	state.dirty = false
+	state.Updated = time.Now()
+
+	progress := pluginModels.UploadProgress{
+		ID:        state.ID,
+		RequestID: state.RequestID,
+		UserID:    state.UserID,
+		Phase:     state.Phase,
+		Size:      state.Size,
+		Uploaded:  state.Uploaded,
+		Processed: state.Processed,
+		Error:     state.Error,
+		CreatedAt: state.Started,
+		UpdatedAt: state.Updated,
+	}
+	if state.Hash != nil {
+		progress.Hash = p.EncodeFileName(state.Hash)
+	}
+	p.uploadsMu.Unlock()
+
+	if err := p.db.Save(&progress).Error; err != nil {
+		p.logger.Error("failed to save upload progress", zap.String("upload_id", progress.ID), zap.Error(err))
+	}
+}
+
+// loadProgress loads the persisted progress of an upload, nil if none was recorded
+func (p *Protocol) loadProgress(uploadID string) (*UploadState, error) {
+	var progress pluginModels.UploadProgress
+	err := p.db.Where("id = ?", uploadID).First(&progress).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return p.progressState(&progress), nil
+}
+
+// progressState builds the state of an upload from its persisted progress
+func (p *Protocol) progressState(progress *pluginModels.UploadProgress) *UploadState {
+	state := &UploadState{
+		ID:        progress.ID,
+		RequestID: progress.RequestID,
+		UserID:    progress.UserID,
+		Size:      progress.Size,
+		Uploaded:  progress.Uploaded,
+		Processed: progress.Processed,
+		Phase:     progress.Phase,
+		Error:     progress.Error,
+		Started:   progress.CreatedAt,
+		Updated:   progress.UpdatedAt,
+		Completed: progress.Phase == handlers.UploadPhaseDone,
+	}
+	if progress.Hash != "" {
+		if hash, err := p.DecodeFileName(progress.Hash); err == nil {
+			state.Hash = hash
+		}
+	}
+
+	return state
+}
+
+// GetUploadStatuses gets the recorded progress of several uploads in a single query, keyed
+// by upload ID. The workflow status is derived from the recorded phase rather than read
+// from each workflow, and uploads without recorded progress are left out.
+func (p *Protocol) GetUploadStatuses(uploadIDs []string) (map[string]*UploadState, error) {
+	states := make(map[string]*UploadState, len(uploadIDs))
+	if len(uploadIDs) == 0 {
+		return states, nil
+	}
+
+	var rows []pluginModels.UploadProgress
+	if err := p.db.Where("id IN ?", uploadIDs).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range rows {
+		state := p.progressState(&rows[i])
+		switch state.Phase {
+		case handlers.UploadPhaseDone:
+			state.Status = string(models.RequestStatusCompleted)
+		case handlers.UploadPhaseFailed:
+			state.Status = string(models.RequestStatusFailed)
+		default:
+			state.Status = uploadStatusProcessing
+		}
+		states[state.ID] = state
+	}
+
+	return states, nil
+}
+
+// uploadProgress returns a snapshot of the progress of an upload, nil if none was recorded.
+// The state kept in memory has fresher byte counters than the persisted one while this
+// node updates it. Otherwise another node has moved the upload on and the stale state
+// is dropped.
+func (p *Protocol) uploadProgress(uploadID string) (*UploadState, error) {
+	persisted, err := p.loadProgress(uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	p.uploadsMu.RLock()
+	local, ok := p.uploads[uploadID]
+	var snapshot UploadState
+	if ok {
+		snapshot = *local
+	}
+	p.uploadsMu.RUnlock()
+
+	if !ok {
+		return persisted, nil
+	}
+
+	if persisted == nil || (persisted.Phase == snapshot.Phase && (snapshot.dirty || !snapshot.Updated.Before(persisted.Updated))) {
+		return &snapshot, nil
+	}
+
+	p.untrackUpload(local)
+	return persisted, nil
+}
+
+// runProgress persists changed byte counters and row counts until stop is closed.
+// Uploads and imports that went idle are dropped from memory, and the progress of those
+// that haven't been updated within the retention period is deleted.
+func (p *Protocol) runProgress(stop <-chan struct{}) {
+	ticker := time.NewTicker(progressFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.flushProgress()
+		case <-stop:
+			p.flushProgress()
+			return
+		}
+	}
+}
+
+// flushProgress persists changed byte counters and expires idle and old uploads,
+// then does the same for imports
+func (p *Protocol) flushProgress() {
+	now := time.Now()
+
+	var dirty, idle []*UploadState
+	p.uploadsMu.RLock()
+	for _, state := range p.uploads {
+		switch {
+		case state.dirty:
+			dirty = append(dirty, state)
+		case now.Sub(state.Updated) > progressIdleTimeout:
+			idle = append(idle, state)
+		}
+	}
+	p.uploadsMu.RUnlock()
+
+	for _, state := range dirty {
+		p.saveProgress(state)
+	}
+	for _, state := range idle {
+		p.untrackUpload(state)
+	}
+
+	// The progress of attached files is kept, as it reports whether they were stored
+	err := p.db.Where("updated_at < ?", now.Add(-progressRetention)).
+		Where("id NOT IN (SELECT upload_id FROM item_attachments)").
+		Delete(&pluginModels.UploadProgress{}).Error
+	if err != nil {
+		p.logger.Error("failed to expire upload progress", zap.Error(err))
+	}
+
+	p.flushImports(now)
+}
+
+// WatchUpload sends the status of an upload whenever it changes until the upload has
+// finished or ctx is done, then closes the returned channel.
+// Phase changes made on this node are sent right away, byte progress and changes
+// made by other nodes are picked up every progressWatchInterval.
+func (p *Protocol) WatchUpload(ctx context.Context, uploadID string) (<-chan *UploadState, error) {
+	state, err := p.GetUploadStatus(uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(chan *UploadState)
+	go func() {
+		defer close(updates)
+
+		ticker := time.NewTicker(progressWatchInterval)
+		defer ticker.Stop()
+
+		var last *UploadState
+		for {
+			if last == nil || uploadChanged(last, state) {
+				select {
+				case updates <- state:
+				case <-ctx.Done():
+					return
+				}
+				last = state
+			}
+			if uploadFinished(state.Phase) {
+				return
+			}
+
+			p.uploadsMu.RLock()
+			changed := p.uploadsChanged
+			p.uploadsMu.RUnlock()
+
+			select {
+			case <-ticker.C:
+			case <-changed:
+			case <-ctx.Done():
+				return
+			}
+
+			next, err := p.GetUploadStatus(uploadID)
+			if err != nil {
+				p.logger.Error("failed to get upload status", zap.String("upload_id", uploadID), zap.Error(err))
+				continue
+			}
+			state = next
+		}
+	}()
+
+	return updates, nil
+}
+
+// uploadChanged reports whether the reported status of an upload differs between two states
+func uploadChanged(a, b *UploadState) bool {
+	return a.Phase != b.Phase ||
+		a.Uploaded != b.Uploaded ||
+		a.Processed != b.Processed ||
+		a.Error != b.Error ||
+		(a.Hash == nil) != (b.Hash == nil)
+}
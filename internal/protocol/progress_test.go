@@ -0,0 +1,211 @@
+package protocol
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+	"time"
+
+	pluginModels "go.lumeweb.com/portal-plugin-template/internal/db/models"
+	"go.lumeweb.com/portal-plugin-template/internal/protocol/handlers"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"gorm.io/gorm"
+)
+
+// newTestNode creates a Protocol tracking uploads in db, standing in for a portal node
+func newTestNode(db *gorm.DB) *Protocol {
+	return &Protocol{
+		db:             db,
+		uploads:        make(map[string]*UploadState),
+		uploadRequests: make(map[uint]*UploadState),
+		uploadsChanged: make(chan struct{}),
+	}
+}
+
+// newTestRequest creates an upload workflow request for size bytes uploaded by userID
+func newTestRequest(id, userID uint, size uint64) *models.Request {
+	digest := sha256.Sum256([]byte("template upload"))
+
+	req := &models.Request{
+		Hash:   core.NewStorageHash(digest[:], uint64(sha256.Size), 0, nil).Multihash(),
+		Size:   size,
+		UserID: userID,
+	}
+	req.ID = id
+
+	return req
+}
+
+func TestUploadProgressAcrossNodes(t *testing.T) {
+	db := newTestDB(t)
+	first, second := newTestNode(db), newTestNode(db)
+	req := newTestRequest(42, 7, 100)
+
+	report := first.StartUploadPhase(req, handlers.UploadPhaseStoring)
+	report(50)
+	first.flushProgress()
+
+	state, err := second.recordedUploadStatus("42")
+	if err != nil {
+		t.Fatalf("recordedUploadStatus() error = %v", err)
+	}
+	if state == nil {
+		t.Fatal("recordedUploadStatus() found no progress recorded by another node")
+	}
+	if state.Phase != handlers.UploadPhaseStoring || state.Processed != 50 || state.Uploaded != 100 || state.UserID != 7 {
+		t.Errorf("recordedUploadStatus() = phase %q, processed %d, uploaded %d, user %d, want storing, 50, 100, 7",
+			state.Phase, state.Processed, state.Uploaded, state.UserID)
+	}
+	if state.Hash == nil {
+		t.Error("recordedUploadStatus() lost the upload's hash")
+	}
+
+	// The node updating the upload reports byte progress before it is persisted
+	report(70)
+	if state, _ := first.uploadProgress("42"); state.Processed != 70 {
+		t.Errorf("uploadProgress() on the updating node processed = %d, want the unpersisted 70", state.Processed)
+	}
+	if state, _ := second.uploadProgress("42"); state.Processed != 50 {
+		t.Errorf("uploadProgress() on another node processed = %d, want the persisted 50", state.Processed)
+	}
+	first.flushProgress()
+
+	// Another node moves the upload on, so the first node's state is stale
+	second.StartUploadPhase(req, handlers.UploadPhaseScanning)
+
+	state, err = first.uploadProgress("42")
+	if err != nil {
+		t.Fatalf("uploadProgress() error = %v", err)
+	}
+	if state.Phase != handlers.UploadPhaseScanning || state.Processed != 0 {
+		t.Errorf("uploadProgress() = phase %q, processed %d, want the persisted scanning, 0", state.Phase, state.Processed)
+	}
+	if _, ok := first.uploads["42"]; ok {
+		t.Error("stale upload state kept in memory after another node moved the upload on")
+	}
+
+	second.FinishUpload(req, nil)
+
+	state, err = first.recordedUploadStatus("42")
+	if err != nil {
+		t.Fatalf("recordedUploadStatus() error = %v", err)
+	}
+	if state.Phase != handlers.UploadPhaseDone || !state.Completed {
+		t.Errorf("recordedUploadStatus() = phase %q, completed %v, want done, true", state.Phase, state.Completed)
+	}
+	if len(second.uploads) != 0 || len(second.uploadRequests) != 0 {
+		t.Error("finished upload kept in memory")
+	}
+}
+
+func TestFinishUploadFailed(t *testing.T) {
+	db := newTestDB(t)
+	first, second := newTestNode(db), newTestNode(db)
+	req := newTestRequest(43, 7, 100)
+
+	first.StartUploadPhase(req, handlers.UploadPhaseStoring)
+	first.FinishUpload(req, errors.New("storage unavailable"))
+
+	state, err := second.recordedUploadStatus("43")
+	if err != nil {
+		t.Fatalf("recordedUploadStatus() error = %v", err)
+	}
+	if state.Phase != handlers.UploadPhaseFailed || state.Completed || state.Error != "storage unavailable" {
+		t.Errorf("recordedUploadStatus() = phase %q, completed %v, error %q, want failed, false, storage unavailable",
+			state.Phase, state.Completed, state.Error)
+	}
+}
+
+func TestRecordedUploadStatusFollowsRequest(t *testing.T) {
+	db := newTestDB(t)
+	first, second := newTestNode(db), newTestNode(db)
+	req := newTestRequest(44, 7, 100)
+
+	// A received resumable upload points to its workflow request
+	first.trackUpload(&UploadState{ID: "tus-upload", UserID: 7, Size: 100, Uploaded: 100, Phase: handlers.UploadPhaseHashing, Started: time.Now()})
+	first.StartUploadPhase(req, handlers.UploadPhaseStoring)
+	first.uploads["tus-upload"].RequestID = req.ID
+	first.saveProgress(first.uploads["tus-upload"])
+
+	state, err := second.recordedUploadStatus("tus-upload")
+	if err != nil {
+		t.Fatalf("recordedUploadStatus() error = %v", err)
+	}
+	if state.ID != "tus-upload" || state.Phase != handlers.UploadPhaseStoring || state.RequestID != req.ID {
+		t.Errorf("recordedUploadStatus() = ID %q, phase %q, request %d, want tus-upload, storing, %d",
+			state.ID, state.Phase, state.RequestID, req.ID)
+	}
+
+	if state, err := second.recordedUploadStatus("missing"); err != nil || state != nil {
+		t.Errorf("recordedUploadStatus() of an unknown upload = %v, %v, want nil, nil", state, err)
+	}
+}
+
+func TestFlushProgressExpiry(t *testing.T) {
+	db := newTestDB(t)
+	p := newTestNode(db)
+	now := time.Now()
+
+	active := &UploadState{ID: "active", Phase: handlers.UploadPhaseReceiving, Started: now}
+	idle := &UploadState{ID: "idle", Phase: handlers.UploadPhaseReceiving, Started: now}
+	p.trackUpload(active)
+	p.trackUpload(idle)
+	idle.Updated = now.Add(-progressIdleTimeout - time.Minute)
+
+	old := pluginModels.UploadProgress{
+		ID:        "old",
+		Phase:     handlers.UploadPhaseDone,
+		CreatedAt: now.Add(-progressRetention - time.Hour),
+		UpdatedAt: now.Add(-progressRetention - time.Hour),
+	}
+	attached := old
+	attached.ID = "12"
+	if err := db.Create([]pluginModels.UploadProgress{old, attached}).Error; err != nil {
+		t.Fatalf("failed to record old progress: %v", err)
+	}
+	if err := db.Create(&pluginModels.ItemAttachment{ItemID: 1, UploadID: 12, Hash: "hash", Name: "manual.pdf"}).Error; err != nil {
+		t.Fatalf("failed to record attachment: %v", err)
+	}
+
+	p.flushProgress()
+
+	if _, ok := p.uploads["idle"]; ok {
+		t.Error("idle upload kept in memory")
+	}
+	if _, ok := p.uploads["active"]; !ok {
+		t.Error("active upload dropped from memory")
+	}
+
+	var ids []string
+	db.Model(&pluginModels.UploadProgress{}).Order("id").Pluck("id", &ids)
+	if len(ids) != 3 || ids[0] != "12" || ids[1] != "active" || ids[2] != "idle" {
+		t.Errorf("persisted progress = %v, want [12 active idle] after the old unattached upload expired", ids)
+	}
+}
+
+func TestGetUploadStatuses(t *testing.T) {
+	db := newTestDB(t)
+	p := newTestNode(db)
+
+	stored := newTestRequest(45, 7, 100)
+	p.StartUploadPhase(stored, handlers.UploadPhaseStoring)
+	p.FinishUpload(stored, nil)
+
+	storing := newTestRequest(46, 7, 100)
+	p.StartUploadPhase(storing, handlers.UploadPhaseStoring)
+
+	states, err := p.GetUploadStatuses([]string{"45", "46", "47"})
+	if err != nil {
+		t.Fatalf("GetUploadStatuses() error = %v", err)
+	}
+	if len(states) != 2 {
+		t.Fatalf("GetUploadStatuses() returned %d states, want 2 for the recorded uploads", len(states))
+	}
+	if state := states["45"]; state.Status != string(models.RequestStatusCompleted) || !state.Completed {
+		t.Errorf("stored upload status = %q, completed %v, want completed, true", state.Status, state.Completed)
+	}
+	if state := states["46"]; state.Status != uploadStatusProcessing || state.Completed {
+		t.Errorf("storing upload status = %q, completed %v, want processing, false", state.Status, state.Completed)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"crypto/sha256"
 	"errors"
 	"fmt"
+	"github.com/tus/tusd/v2/pkg/filestore"
+	tusd "github.com/tus/tusd/v2/pkg/handler"
 	"go.lumeweb.com/portal-plugin-template/internal/protocol/request"
 	"io"
 	"strconv"
@@ -20,6 +22,7 @@ import (
 	"go.lumeweb.com/portal/core"
 	"go.lumeweb.com/portal/db/models"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 var (
@@ -36,23 +39,46 @@ type Protocol struct {
 	itemService  core.Service
 	storage      core.StorageService
 	coordinator  core.WorkflowCoordinator
+	db           *gorm.DB
 	ctx          core.Context
 
 	// Internal state
-	uploads   map[string]*uploadState
-	uploadsMu sync.RWMutex
-	isRunning bool
+	uploads        map[string]*UploadState
+	uploadRequests map[uint]*UploadState
+	uploadsMu      sync.RWMutex
+	uploadsChanged chan struct{} // Closed and replaced whenever an upload changes phase
+	imports        map[string]*importState
+	importsMu      sync.RWMutex
+	isRunning      bool
+
+	// Upload data waiting for the upload workflow
+	uploadStorage TemporaryStorage
+
+	// Resumable uploads
+	tus      *tusd.UnroutedHandler
+	tusStore filestore.FileStore
+
+	stop chan struct{}
 }
 
-
-// uploadState tracks an ongoing upload
-type uploadState struct {
+// UploadState tracks the progress of an upload
+// States are kept in memory by the node updating them and persisted as UploadProgress rows.
+type UploadState struct {
 	ID        string
+	RequestID uint
+	UserID    uint
 	Size      uint64
 	Uploaded  uint64
+	Processed uint64
+	Phase     string
+	Error     string
 	Started   time.Time
+	Updated   time.Time
 	Completed bool
+	Status    string
 	Hash      core.StorageHash
+
+	dirty bool // Whether the byte counters changed since the state was persisted
 }
 
 func (p *Protocol) Name() string {
@@ -80,7 +106,10 @@ func (p *Protocol) Operations() []core.Operation {
 
 func NewProtocol() (*Protocol, []core.ContextBuilderOption, error) {
 	proto := &Protocol{
-		uploads: make(map[string]*uploadState),
+		uploads:        make(map[string]*UploadState),
+		uploadRequests: make(map[uint]*UploadState),
+		uploadsChanged: make(chan struct{}),
+		imports:        make(map[string]*importState),
 	}
 
 	opts := core.ContextOptions(
@@ -91,10 +120,18 @@ func NewProtocol() (*Protocol, []core.ContextBuilderOption, error) {
 			proto.storage = ctx.Service(core.STORAGE_SERVICE).(core.StorageService)
 			proto.itemService = core.GetService[service.ItemService](ctx, service.ITEM_SERVICE)
 			proto.coordinator = ctx.Service("workflow").(core.WorkflowCoordinator)
+			proto.db = ctx.DB()
 
 			// Load config
 			cfg := proto.portalConfig.GetProtocol(internal.PLUGIN_NAME).(*pluginConfig.Config)
 			proto.config = cfg
+			proto.uploadStorage = newS3Storage(proto.storage, proto)
+
+			tus, err := proto.newTusHandler()
+			if err != nil {
+				return fmt.Errorf("failed to create tus handler: %w", err)
+			}
+			proto.tus = tus
 
 			// Get request service
 			requestSvc := ctx.Service(core.REQUEST_SERVICE).(core.RequestService)
@@ -121,12 +158,19 @@ func NewProtocol() (*Protocol, []core.ContextBuilderOption, error) {
 
 func (p *Protocol) Start(_ core.Context) error {
 	p.logger.Info("Starting template protocol")
+	p.stop = make(chan struct{})
+	go p.handleTusEvents(p.stop)
+	go p.runProgress(p.stop)
 	p.isRunning = true
 	return nil
 }
 
 func (p *Protocol) Stop(_ core.Context) error {
 	p.logger.Info("Stopping template protocol")
+	if p.stop != nil {
+		close(p.stop)
+		p.stop = nil
+	}
 	p.isRunning = false
 	return nil
 }
@@ -145,48 +189,189 @@ func (p *Protocol) Hash(r io.Reader, _ uint64) (core.StorageHash, error) {
 }
 
 func (p *Protocol) HandleUpload(ctx context.Context, reader io.Reader, size uint64) (core.StorageHash, error) {
+	state, err := p.Upload(ctx, 0, reader, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return state.Hash, nil
+}
+
+// StagedUpload is an upload held in temporary storage whose workflow hasn't started yet
+type StagedUpload struct {
+	source *UploadState
+	hash   core.StorageHash
+}
+
+// Hash returns the content hash of the staged upload
+func (s *StagedUpload) Hash() core.StorageHash {
+	return s.hash
+}
+
+// Size returns the size of the staged upload in bytes
+func (s *StagedUpload) Size() uint64 {
+	return s.source.Size
+}
+
+// Upload stores an upload in temporary storage while hashing it and starts the upload
+// workflow for it on behalf of a user. The workflow only starts once the data matched the
+// declared size and was committed, ErrSizeMismatch is returned if it didn't match.
+// Returns the tracked upload state, whose ID is the workflow request ID.
+// The upload is only tracked once the workflow started, as its ID doesn't exist before,
+// so direct uploads begin reporting their progress at the storing phase.
+func (p *Protocol) Upload(ctx context.Context, userID uint, reader io.Reader, size uint64) (*UploadState, error) {
+	staged, err := p.StageUpload(ctx, userID, reader, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.StartUpload(ctx, staged)
+}
+
+// StageUpload stores an upload in temporary storage while hashing it on behalf of a user,
+// without starting its workflow, so callers can record what the upload is for first.
+// Returns ErrSizeMismatch if the data didn't match the declared size. The staged upload
+// must be passed to StartUpload or DiscardUpload.
+func (p *Protocol) StageUpload(ctx context.Context, userID uint, reader io.Reader, size uint64) (*StagedUpload, error) {
+	return p.stage(ctx, &UploadState{
+		UserID:  userID,
+		Size:    size,
+		Phase:   handlers.UploadPhaseReceiving,
+		Started: time.Now(),
+	}, reader)
+}
+
+// DiscardUpload releases a staged upload whose workflow won't be started
+func (p *Protocol) DiscardUpload(ctx context.Context, staged *StagedUpload) error {
+	return p.releaseUpload(ctx, uploadKey(staged.hash))
+}
+
+// upload stages an upload, counting the bytes read as processed by the source state's
+// current phase, and starts the upload workflow for it.
+// Returns the upload state of the workflow request.
+func (p *Protocol) upload(ctx context.Context, source *UploadState, reader io.Reader) (*UploadState, error) {
+	staged, err := p.stage(ctx, source, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.StartUpload(ctx, staged)
+}
+
+// stage stores an upload in temporary storage, counting the bytes read as processed by
+// the source state's current phase
+func (p *Protocol) stage(ctx context.Context, source *UploadState, reader io.Reader) (*StagedUpload, error) {
 	if !p.isRunning {
 		return nil, errors.New("protocol not running")
 	}
 
-	// Calculate hash first
-	h := sha256.New()
-	if _, err := io.Copy(h, reader); err != nil {
-		return nil, fmt.Errorf("failed to calculate hash: %w", err)
+	hash, staging, err := stageUpload(ctx, p.uploadStorage, reader, source.Size, &progressWriter{p: p, state: source})
+	if err != nil {
+		return nil, err
 	}
 
-	// Create storage hash
-	hash := core.NewStorageHash(h.Sum(nil), uint64(sha256.Size), 0, nil)
+	if err := p.commitUpload(ctx, staging, hash); err != nil {
+		return nil, err
+	}
+
+	return &StagedUpload{source: source, hash: hash}, nil
+}
+
+// StartUpload starts the upload workflow for a staged upload, releasing the upload if the
+// workflow can't be started.
+// Returns the tracked upload state, whose ID is the workflow request ID.
+func (p *Protocol) StartUpload(ctx context.Context, staged *StagedUpload) (*UploadState, error) {
+	source := staged.source
 
 	// Start upload workflow
 	req := &models.Request{
 		Protocol: p.Name(),
-		Hash:     hash.Multihash(),
-		Size:     size,
+		Hash:     staged.hash.Multihash(),
+		Size:     source.Size,
+		UserID:   source.UserID,
 	}
 
-	_, err := p.coordinator.StartWorkflow(ctx, workflow.WorkflowUpload, req)
-	if err != nil {
+	if _, err := p.coordinator.StartWorkflow(ctx, workflow.WorkflowUpload, req); err != nil {
+		_ = p.DiscardUpload(ctx, staged)
 		return nil, fmt.Errorf("failed to start upload workflow: %w", err)
 	}
 
-	// Track upload state
-	state := &uploadState{
-		ID:      fmt.Sprintf("%d", req.ID),
-		Size:    size,
-		Started: time.Now(),
-		Hash:    hash,
-	}
-
+	// The workflow may already have moved the upload on
+	state := p.requestUpload(req)
 	p.uploadsMu.Lock()
-	p.uploads[state.ID] = state
+	if state.Phase == "" {
+		state.Phase = handlers.UploadPhaseStoring
+	}
+	state.Started = source.Started
 	p.uploadsMu.Unlock()
 
-	return hash, nil
+	p.saveProgress(state)
+	if uploadFinished(state.Phase) {
+		p.untrackUpload(state)
+	}
+
+	return state, nil
+}
+
+// GetUploadStatus gets the progress of an upload along with its workflow status.
+// Received resumable uploads continue under their workflow request, so their tus ID
+// reports the progress of the workflow. Uploads without recorded progress report their
+// workflow status only.
+func (p *Protocol) GetUploadStatus(uploadID string) (*UploadState, error) {
+	state, err := p.recordedUploadStatus(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return p.workflowUploadStatus(uploadID)
+	}
+
+	if state.RequestID != 0 {
+		status, err := p.coordinator.GetWorkflowStatus(context.Background(), state.RequestID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get workflow status: %w", err)
+		}
+		state.Status = status.Status
+
+		// The workflow may end in a step that doesn't track the upload
+		if !uploadFinished(state.Phase) {
+			switch status.Status {
+			case string(models.RequestStatusCompleted):
+				state.Phase = handlers.UploadPhaseDone
+				state.Completed = true
+			case string(models.RequestStatusFailed):
+				state.Phase = handlers.UploadPhaseFailed
+			}
+		}
+	}
+
+	return state, nil
 }
 
-// GetUploadStatus gets the status of an upload from its workflow state
-func (p *Protocol) GetUploadStatus(uploadID string) (*uploadState, error) {
+// recordedUploadStatus returns the recorded progress of an upload, nil if none was recorded.
+// The progress of a received resumable upload is that of its workflow request.
+func (p *Protocol) recordedUploadStatus(uploadID string) (*UploadState, error) {
+	state, err := p.uploadProgress(uploadID)
+	if err != nil || state == nil {
+		return state, err
+	}
+
+	if requestID := strconv.FormatUint(uint64(state.RequestID), 10); state.RequestID != 0 && state.ID != requestID {
+		next, err := p.uploadProgress(requestID)
+		if err != nil {
+			return nil, err
+		}
+		if next != nil {
+			next.ID = uploadID
+			state = next
+		}
+	}
+
+	return state, nil
+}
+
+// workflowUploadStatus builds the status of an upload from its workflow state
+func (p *Protocol) workflowUploadStatus(uploadID string) (*UploadState, error) {
 	requestID, err := strconv.ParseUint(uploadID, 10, 64)
 	if err != nil {
 		return nil, fmt.Errorf("invalid upload ID: %w", err)
@@ -204,11 +389,25 @@ func (p *Protocol) GetUploadStatus(uploadID string) (*uploadState, error) {
 		return nil, fmt.Errorf("failed to get request: %w", err)
 	}
 
-	return &uploadState{
+	phase := handlers.UploadPhaseStoring
+	switch status.Status {
+	case string(models.RequestStatusCompleted):
+		phase = handlers.UploadPhaseDone
+	case string(models.RequestStatusFailed):
+		phase = handlers.UploadPhaseFailed
+	}
+
+	// The workflow only starts once all data has been received
+	return &UploadState{
 		ID:        uploadID,
+		RequestID: req.ID,
+		UserID:    req.UserID,
 		Size:      req.Size,
+		Uploaded:  req.Size,
+		Phase:     phase,
 		Started:   status.StartedAt,
-		Completed: status.Status == string(models.RequestStatusCompleted),
+		Completed: phase == handlers.UploadPhaseDone,
+		Status:    status.Status,
 		Hash:      core.NewStorageHashFromMultihashBytes(req.Hash, 0, nil),
 	}, nil
 }
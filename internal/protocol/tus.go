@@ -0,0 +1,230 @@
+package protocol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/tus/tusd/v2/pkg/filelocker"
+	"github.com/tus/tusd/v2/pkg/filestore"
+	tusd "github.com/tus/tusd/v2/pkg/handler"
+	pluginModels "go.lumeweb.com/portal-plugin-template/internal/db/models"
+	"go.lumeweb.com/portal-plugin-template/internal/protocol/handlers"
+	"go.uber.org/zap"
+)
+
+const (
+	// TusBasePath is the path the tus endpoint is mounted under
+	TusBasePath = "/api/tus/"
+
+	// tusMetaUserID is the upload metadata key recording who created a tus upload
+	tusMetaUserID = "user_id"
+)
+
+// uploaderKey is the context key carrying the user creating a tus upload
+type uploaderKey struct{}
+
+// WithUploader returns a context recording the user a tus request is made on behalf of
+func WithUploader(ctx context.Context, userID uint) context.Context {
+	return context.WithValue(ctx, uploaderKey{}, userID)
+}
+
+// uploaderFromContext returns the user recorded by WithUploader
+func uploaderFromContext(ctx context.Context) (uint, bool) {
+	userID, ok := ctx.Value(uploaderKey{}).(uint)
+	return userID, ok
+}
+
+// newTusHandler creates the tus handler, keeping partial uploads under the storage path
+func (p *Protocol) newTusHandler() (*tusd.UnroutedHandler, error) {
+	dir := filepath.Join(p.config.StoragePath, "tus")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create tus directory: %w", err)
+	}
+
+	p.tusStore = filestore.New(dir)
+
+	composer := tusd.NewStoreComposer()
+	p.tusStore.UseIn(composer)
+	filelocker.New(dir).UseIn(composer)
+
+	return tusd.NewUnroutedHandler(tusd.Config{
+		BasePath:                TusBasePath,
+		StoreComposer:           composer,
+		MaxSize:                 p.config.MaxUploadSize,
+		NotifyCreatedUploads:    true,
+		NotifyUploadProgress:    true,
+		NotifyCompleteUploads:   true,
+		NotifyTerminatedUploads: true,
+		PreUploadCreateCallback: p.preTusUploadCreate,
+	})
+}
+
+// TusHandler returns the handler serving the tus resumable upload protocol.
+// Requests must carry the uploading user, see WithUploader.
+func (p *Protocol) TusHandler() *tusd.UnroutedHandler {
+	return p.tus
+}
+
+// TusUploadOwner returns the user who created a tus upload
+func (p *Protocol) TusUploadOwner(ctx context.Context, id string) (uint, error) {
+	upload, err := p.tusStore.GetUpload(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	info, err := upload.GetInfo(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	userID, err := strconv.ParseUint(info.MetaData[tusMetaUserID], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("tus upload has no valid uploader: %w", err)
+	}
+
+	return uint(userID), nil
+}
+
+// preTusUploadCreate records the uploading user on a new tus upload.
+// Any user ID sent by the client is overwritten.
+func (p *Protocol) preTusUploadCreate(hook tusd.HookEvent) (tusd.HTTPResponse, tusd.FileInfoChanges, error) {
+	userID, ok := uploaderFromContext(hook.Context)
+	if !ok {
+		return tusd.HTTPResponse{}, tusd.FileInfoChanges{}, errors.New("tus upload has no uploader")
+	}
+
+	metadata := make(tusd.MetaData, len(hook.Upload.MetaData)+1)
+	for key, value := range hook.Upload.MetaData {
+		metadata[key] = value
+	}
+	metadata[tusMetaUserID] = strconv.FormatUint(uint64(userID), 10)
+
+	return tusd.HTTPResponse{}, tusd.FileInfoChanges{MetaData: metadata}, nil
+}
+
+// handleTusEvents tracks tus uploads until stop is closed.
+// tusd blocks until its notifications are received, so they are always drained.
+func (p *Protocol) handleTusEvents(stop <-chan struct{}) {
+	for {
+		select {
+		case event := <-p.tus.CreatedUploads:
+			p.tusState(event.Upload)
+		case event := <-p.tus.UploadProgress:
+			state := p.tusState(event.Upload)
+			p.uploadsMu.Lock()
+			state.Uploaded = uint64(event.Upload.Offset)
+			state.Processed = state.Uploaded
+			state.dirty = true
+			p.uploadsMu.Unlock()
+		case event := <-p.tus.CompleteUploads:
+			// Hashing can take a while, keep draining the other notifications meanwhile
+			go p.completeTusUpload(event.Upload)
+		case event := <-p.tus.TerminatedUploads:
+			p.removeTusState(event.Upload)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// tusState returns the tracked state of a tus upload, creating it if needed
+func (p *Protocol) tusState(info tusd.FileInfo) *UploadState {
+	p.uploadsMu.RLock()
+	state, ok := p.uploads[info.ID]
+	p.uploadsMu.RUnlock()
+	if ok {
+		return state
+	}
+
+	userID, _ := strconv.ParseUint(info.MetaData[tusMetaUserID], 10, 64)
+	state = &UploadState{
+		ID:       info.ID,
+		UserID:   uint(userID),
+		Size:     uint64(info.Size),
+		Uploaded: uint64(info.Offset),
+		Phase:    handlers.UploadPhaseReceiving,
+		Started:  time.Now(),
+	}
+	p.trackUpload(state)
+
+	return state
+}
+
+// removeTusState stops tracking a cancelled tus upload and deletes its progress
+func (p *Protocol) removeTusState(info tusd.FileInfo) {
+	p.uploadsMu.RLock()
+	state, ok := p.uploads[info.ID]
+	p.uploadsMu.RUnlock()
+	if !ok || state.Phase != handlers.UploadPhaseReceiving {
+		return
+	}
+
+	p.untrackUpload(state)
+	if err := p.db.Where("id = ?", info.ID).Delete(&pluginModels.UploadProgress{}).Error; err != nil {
+		p.logger.Error("failed to delete tus upload progress", zap.String("tus_id", info.ID), zap.Error(err))
+	}
+}
+
+// completeTusUpload hashes a fully received tus upload and feeds it into the upload workflow.
+// Afterwards the tus ID resolves to the workflow's upload state, and the partial
+// upload is removed from the tus store.
+func (p *Protocol) completeTusUpload(info tusd.FileInfo) {
+	state := p.tusState(info)
+	ctx := context.Background()
+
+	fail := func(msg string, err error) {
+		p.logger.Error(msg, zap.String("tus_id", info.ID), zap.Error(err))
+		p.setUploadPhase(state, handlers.UploadPhaseFailed, err)
+	}
+
+	p.setUploadPhase(state, handlers.UploadPhaseHashing, nil)
+
+	userID, err := strconv.ParseUint(info.MetaData[tusMetaUserID], 10, 64)
+	if err != nil {
+		fail("tus upload has no valid uploader", err)
+		return
+	}
+
+	upload, err := p.tusStore.GetUpload(ctx, info.ID)
+	if err != nil {
+		fail("failed to get tus upload", err)
+		return
+	}
+
+	reader, err := upload.GetReader(ctx)
+	if err != nil {
+		fail("failed to read tus upload", err)
+		return
+	}
+
+	p.uploadsMu.Lock()
+	state.UserID = uint(userID)
+	state.Uploaded = uint64(info.Size)
+	p.uploadsMu.Unlock()
+
+	result, err := p.upload(ctx, state, reader)
+	if closeErr := reader.Close(); closeErr != nil {
+		p.logger.Error("failed to close tus upload reader", zap.String("tus_id", info.ID), zap.Error(closeErr))
+	}
+	if err != nil {
+		fail("failed to start upload workflow for tus upload", err)
+		return
+	}
+
+	// The tus ID now points to the workflow request, which this node no longer tracks under it
+	p.uploadsMu.Lock()
+	state.RequestID = result.RequestID
+	state.Hash = result.Hash
+	p.uploadsMu.Unlock()
+	p.saveProgress(state)
+	p.untrackUpload(state)
+
+	if err := p.tusStore.AsTerminatableUpload(upload).Terminate(ctx); err != nil {
+		p.logger.Error("failed to remove tus upload", zap.String("tus_id", info.ID), zap.Error(err))
+	}
+}
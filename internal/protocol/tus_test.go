@@ -0,0 +1,43 @@
+package protocol
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tus/tusd/v2/pkg/filestore"
+	tusd "github.com/tus/tusd/v2/pkg/handler"
+)
+
+func TestTusUploadOwner(t *testing.T) {
+	ctx := context.Background()
+	p := &Protocol{tusStore: filestore.New(t.TempDir())}
+
+	create := func(metadata tusd.MetaData) string {
+		upload, err := p.tusStore.NewUpload(ctx, tusd.FileInfo{Size: 4, MetaData: metadata})
+		if err != nil {
+			t.Fatalf("NewUpload() error = %v", err)
+		}
+		info, err := upload.GetInfo(ctx)
+		if err != nil {
+			t.Fatalf("GetInfo() error = %v", err)
+		}
+		return info.ID
+	}
+
+	owned := create(tusd.MetaData{tusMetaUserID: "7", "filename": "data.bin"})
+	owner, err := p.TusUploadOwner(ctx, owned)
+	if err != nil {
+		t.Fatalf("TusUploadOwner() error = %v", err)
+	}
+	if owner != 7 {
+		t.Errorf("TusUploadOwner() = %d, want 7", owner)
+	}
+
+	if _, err := p.TusUploadOwner(ctx, create(tusd.MetaData{"filename": "data.bin"})); err == nil {
+		t.Error("TusUploadOwner() found an owner for an upload without one")
+	}
+
+	if _, err := p.TusUploadOwner(ctx, "missing"); err == nil {
+		t.Error("TusUploadOwner() found an owner for a missing upload")
+	}
+}
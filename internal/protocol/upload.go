@@ -0,0 +1,206 @@
+package protocol
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	pluginModels "go.lumeweb.com/portal-plugin-template/internal/db/models"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrSizeMismatch is returned when the data of an upload doesn't match its declared size
+var ErrSizeMismatch = errors.New("upload size mismatch")
+
+// TemporaryStorage keeps the data of an upload until the upload workflow has stored it
+type TemporaryStorage interface {
+	// Put stores size bytes of data under key, the data is committed once Put returns without error
+	Put(ctx context.Context, key string, data io.Reader, size uint64) error
+	// Open opens the data stored under key
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the data stored under key
+	Delete(ctx context.Context, key string) error
+}
+
+// s3Storage is a TemporaryStorage keeping data in the portal's S3 temporary upload area,
+// which every portal node shares, so the workflow can store an upload on any node
+type s3Storage struct {
+	storage  core.StorageService
+	protocol core.StorageProtocol
+}
+
+// newS3Storage creates a TemporaryStorage keeping the uploads of protocol in S3 temporary storage
+func newS3Storage(storage core.StorageService, protocol core.StorageProtocol) *s3Storage {
+	return &s3Storage{storage: storage, protocol: protocol}
+}
+
+func (s *s3Storage) Put(ctx context.Context, key string, data io.Reader, size uint64) error {
+	return s.storage.S3TemporaryUpload(ctx, s.protocol, key, data, size)
+}
+
+func (s *s3Storage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	return s.storage.S3GetTemporaryUpload(ctx, s.protocol, key)
+}
+
+func (s *s3Storage) Delete(ctx context.Context, key string) error {
+	return s.storage.S3DeleteTemporaryUpload(ctx, s.protocol, key)
+}
+
+// byteCounter counts the bytes written to it
+type byteCounter uint64
+
+func (c *byteCounter) Write(b []byte) (int, error) {
+	*c += byteCounter(len(b))
+	return len(b), nil
+}
+
+// uploadKey returns the temporary storage key of the upload with the given content hash
+func uploadKey(hash core.StorageHash) string {
+	return hex.EncodeToString(hash.Multihash())
+}
+
+// stageUpload copies an upload into temporary storage while hashing it, in a single pass.
+// The data is kept under a staging key, as its hash is only known once it has been read,
+// and is removed again if it doesn't match the declared size. Every byte read is also
+// written to progress.
+// Returns the content hash and the staging key.
+func stageUpload(ctx context.Context, storage TemporaryStorage, data io.Reader, size uint64, progress io.Writer) (core.StorageHash, string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return nil, "", fmt.Errorf("failed to generate staging key: %w", err)
+	}
+	staging := "staging-" + hex.EncodeToString(random)
+
+	// Read one byte past the declared size so oversized bodies are detected without reading them entirely
+	h := sha256.New()
+	var received byteCounter
+	tee := io.TeeReader(io.LimitReader(data, int64(size)+1), io.MultiWriter(h, &received, progress))
+
+	if err := storage.Put(ctx, staging, tee, size); err != nil {
+		return nil, "", fmt.Errorf("failed to store upload: %w", err)
+	}
+
+	if uint64(received) != size {
+		if err := storage.Delete(ctx, staging); err != nil {
+			return nil, "", fmt.Errorf("failed to remove upload: %w", err)
+		}
+		if uint64(received) > size {
+			return nil, "", fmt.Errorf("%w: received more than the declared %d bytes", ErrSizeMismatch, size)
+		}
+		return nil, "", fmt.Errorf("%w: received %d bytes, declared %d", ErrSizeMismatch, received, size)
+	}
+
+	return core.NewStorageHash(h.Sum(nil), uint64(sha256.Size), 0, nil), staging, nil
+}
+
+// commitUpload takes a reference to the staged data of an upload under its content hash.
+// Identical uploads share the data staged first, so the staged copy of a duplicate is removed again.
+func (p *Protocol) commitUpload(ctx context.Context, staging string, hash core.StorageHash) error {
+	return p.commitData(ctx, staging, uploadKey(hash))
+}
+
+// commitData takes a reference to staged data under key, removing the staged copy
+// if identical data was recorded under key before
+func (p *Protocol) commitData(ctx context.Context, staging, key string) error {
+	shared, err := p.acquireUploadData(key, staging)
+	if err != nil || shared {
+		if deleteErr := p.uploadStorage.Delete(ctx, staging); deleteErr != nil {
+			p.logger.Error("failed to remove staged upload", zap.String("key", staging), zap.Error(deleteErr))
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to commit upload: %w", err)
+	}
+
+	return nil
+}
+
+// acquireUploadData takes a reference to the data of an upload, recording it under
+// storageKey unless an identical upload already did. Reports whether existing data is shared.
+func (p *Protocol) acquireUploadData(hash, storageKey string) (bool, error) {
+	shared, err := p.shareUploadData(hash)
+	if err != nil || shared {
+		return shared, err
+	}
+
+	data := pluginModels.UploadData{Hash: hash, StorageKey: storageKey, Refs: 1}
+	if err := p.db.Create(&data).Error; err != nil {
+		// An identical upload may have recorded its data meanwhile
+		if shared, _ := p.shareUploadData(hash); shared {
+			return true, nil
+		}
+		return false, err
+	}
+
+	return false, nil
+}
+
+// shareUploadData takes another reference to recorded upload data, reporting whether there was any
+func (p *Protocol) shareUploadData(hash string) (bool, error) {
+	result := p.db.Model(&pluginModels.UploadData{}).Where("hash = ?", hash).Update("refs", gorm.Expr("refs + 1"))
+	return result.RowsAffected > 0, result.Error
+}
+
+// uploadData returns the record of the temporary data recorded under key
+func (p *Protocol) uploadData(key string) (*pluginModels.UploadData, error) {
+	var data pluginModels.UploadData
+	if err := p.db.Where("hash = ?", key).First(&data).Error; err != nil {
+		return nil, fmt.Errorf("failed to find upload data: %w", err)
+	}
+
+	return &data, nil
+}
+
+// OpenUpload opens the temporary data of an upload
+func (p *Protocol) OpenUpload(ctx context.Context, req *models.Request) (io.ReadCloser, error) {
+	data, err := p.uploadData(uploadKey(core.NewStorageHashFromMultihashBytes(req.Hash, 0, nil)))
+	if err != nil {
+		return nil, err
+	}
+
+	return p.uploadStorage.Open(ctx, data.StorageKey)
+}
+
+// RemoveUpload deletes the temporary data of an upload once no other upload uses it
+func (p *Protocol) RemoveUpload(ctx context.Context, req *models.Request) error {
+	return p.releaseUpload(ctx, uploadKey(core.NewStorageHashFromMultihashBytes(req.Hash, 0, nil)))
+}
+
+// releaseUpload drops a reference to temporary upload data, deleting it with the last one.
+// The data can't be taken over by another upload in between, as the record is only
+// deleted while no upload references it.
+func (p *Protocol) releaseUpload(ctx context.Context, hash string) error {
+	var data pluginModels.UploadData
+	if err := p.db.Where("hash = ?", hash).First(&data).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to find upload data: %w", err)
+	}
+
+	if err := p.db.Model(&data).Update("refs", gorm.Expr("refs - 1")).Error; err != nil {
+		return fmt.Errorf("failed to release upload data: %w", err)
+	}
+
+	result := p.db.Where("hash = ? AND refs <= 0", hash).Delete(&pluginModels.UploadData{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to remove upload data record: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	if err := p.uploadStorage.Delete(ctx, data.StorageKey); err != nil {
+		p.logger.Error("failed to remove upload data", zap.String("key", data.StorageKey), zap.Error(err))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,249 @@
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	pluginModels "go.lumeweb.com/portal-plugin-template/internal/db/models"
+	"go.lumeweb.com/portal/core"
+	"go.lumeweb.com/portal/db/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// memoryStorage is an in-memory TemporaryStorage standing in for the storage service
+type memoryStorage struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	putErr  error
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{objects: make(map[string][]byte)}
+}
+
+func (s *memoryStorage) Put(_ context.Context, key string, data io.Reader, _ uint64) error {
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, data); err != nil {
+		return err
+	}
+	if s.putErr != nil {
+		return s.putErr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = buf.Bytes()
+	return nil
+}
+
+func (s *memoryStorage) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memoryStorage) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.objects, key)
+	return nil
+}
+
+// newTestDB opens a SQLite database holding the plugin's upload and import tables.
+// Protocols sharing it behave like portal nodes sharing their database.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "portal.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := db.AutoMigrate(&pluginModels.UploadData{}, &pluginModels.UploadProgress{}, &pluginModels.ImportProgress{}, &pluginModels.ItemAttachment{}); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	return db
+}
+
+// onePassReader fails if it is read again after reaching EOF
+type onePassReader struct {
+	r   io.Reader
+	eof bool
+}
+
+func (r *onePassReader) Read(b []byte) (int, error) {
+	if r.eof {
+		return 0, errors.New("read after EOF")
+	}
+	n, err := r.r.Read(b)
+	if err == io.EOF {
+		r.eof = true
+	}
+	return n, err
+}
+
+func TestStageUpload(t *testing.T) {
+	ctx := context.Background()
+	data := []byte(strings.Repeat("template upload ", 4096))
+	digest := sha256.Sum256(data)
+
+	storage := newMemoryStorage()
+	var progress byteCounter
+	hash, staging, err := stageUpload(ctx, storage, &onePassReader{r: bytes.NewReader(data)}, uint64(len(data)), &progress)
+	if err != nil {
+		t.Fatalf("stageUpload() error = %v", err)
+	}
+
+	if uint64(progress) != uint64(len(data)) {
+		t.Errorf("stageUpload() reported %d bytes of progress, want %d", progress, len(data))
+	}
+
+	if !bytes.Equal(hash.Multihash()[len(hash.Multihash())-sha256.Size:], digest[:]) {
+		t.Errorf("stageUpload() hash = %x, want digest %x", hash.Multihash(), digest)
+	}
+
+	reader, err := storage.Open(ctx, staging)
+	if err != nil {
+		t.Fatalf("staged data not stored: %v", err)
+	}
+	stored, _ := io.ReadAll(reader)
+	if !bytes.Equal(stored, data) {
+		t.Errorf("staged data has %d bytes, want the %d uploaded bytes", len(stored), len(data))
+	}
+}
+
+func TestStageUploadSizeMismatch(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		data string
+		size uint64
+	}{
+		{name: "body shorter than declared", data: "short", size: 10},
+		{name: "body longer than declared", data: "longer than declared", size: 4},
+		{name: "empty body with declared size", data: "", size: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newMemoryStorage()
+
+			_, _, err := stageUpload(ctx, storage, strings.NewReader(tt.data), tt.size, io.Discard)
+			if !errors.Is(err, ErrSizeMismatch) {
+				t.Fatalf("stageUpload() error = %v, want ErrSizeMismatch", err)
+			}
+
+			if len(storage.objects) != 0 {
+				t.Errorf("storage holds %d objects after a size mismatch, want none", len(storage.objects))
+			}
+		})
+	}
+}
+
+func TestStageUploadStorageError(t *testing.T) {
+	storage := newMemoryStorage()
+	storage.putErr = errors.New("storage unavailable")
+
+	_, _, err := stageUpload(context.Background(), storage, strings.NewReader("data"), 4, io.Discard)
+	if !errors.Is(err, storage.putErr) {
+		t.Fatalf("stageUpload() error = %v, want %v", err, storage.putErr)
+	}
+}
+
+func TestStageUploadEmpty(t *testing.T) {
+	storage := newMemoryStorage()
+
+	hash, staging, err := stageUpload(context.Background(), storage, strings.NewReader(""), 0, io.Discard)
+	if err != nil {
+		t.Fatalf("stageUpload() error = %v", err)
+	}
+	if hash == nil {
+		t.Fatal("stageUpload() returned no hash")
+	}
+	if data, ok := storage.objects[staging]; !ok || len(data) != 0 {
+		t.Errorf("staged data = %q, %v, want an empty object", data, ok)
+	}
+}
+
+func TestCommitUploadSharesData(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	storage := newMemoryStorage()
+
+	// Two nodes sharing the database and the temporary storage
+	first := &Protocol{db: db, uploadStorage: storage}
+	second := &Protocol{db: db, uploadStorage: storage}
+
+	data := "identical upload data"
+	commit := func(p *Protocol) core.StorageHash {
+		hash, staging, err := stageUpload(ctx, storage, strings.NewReader(data), uint64(len(data)), io.Discard)
+		if err != nil {
+			t.Fatalf("stageUpload() error = %v", err)
+		}
+		if err := p.commitUpload(ctx, staging, hash); err != nil {
+			t.Fatalf("commitUpload() error = %v", err)
+		}
+		return hash
+	}
+
+	hash := commit(first)
+	commit(second)
+
+	if len(storage.objects) != 1 {
+		t.Fatalf("storage holds %d copies of identical uploads, want 1", len(storage.objects))
+	}
+
+	// Any node can read the data, whichever node staged it
+	req := &models.Request{Hash: hash.Multihash()}
+	reader, err := second.OpenUpload(ctx, req)
+	if err != nil {
+		t.Fatalf("OpenUpload() error = %v", err)
+	}
+	stored, _ := io.ReadAll(reader)
+	if string(stored) != data {
+		t.Errorf("OpenUpload() data = %q, want %q", stored, data)
+	}
+
+	if err := second.RemoveUpload(ctx, req); err != nil {
+		t.Fatalf("RemoveUpload() error = %v", err)
+	}
+	if len(storage.objects) != 1 {
+		t.Fatal("data removed while another upload still uses it")
+	}
+
+	// A restarted node releases the remaining reference
+	restarted := &Protocol{db: db, uploadStorage: storage}
+	if err := restarted.RemoveUpload(ctx, req); err != nil {
+		t.Fatalf("RemoveUpload() error = %v", err)
+	}
+	if len(storage.objects) != 0 {
+		t.Errorf("storage holds %d objects after the last upload was removed, want none", len(storage.objects))
+	}
+
+	var records int64
+	db.Model(&pluginModels.UploadData{}).Count(&records)
+	if records != 0 {
+		t.Errorf("%d upload data records left, want none", records)
+	}
+
+	// Releasing data that is already gone is a no-op
+	if err := restarted.RemoveUpload(ctx, req); err != nil {
+		t.Errorf("RemoveUpload() of removed data error = %v", err)
+	}
+}
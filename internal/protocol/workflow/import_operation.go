@@ -0,0 +1,10 @@
+package workflow
+
+import (
+	"go.lumeweb.com/portal-plugin-template/internal/protocol/handlers"
+	"go.lumeweb.com/portal/core"
+)
+
+func NewImportOperationHandler(protocol core.Protocol, ctx core.Context) core.OperationHandler {
+	return handlers.NewImportHandler(protocol, ctx)
+}
@@ -8,6 +8,7 @@ import (
 
 const (
 	WorkflowUpload = "template.upload"
+	WorkflowImport = "template.import"
 )
 
 // RegisterWorkflows registers all workflows for the template protocol
@@ -25,6 +26,16 @@ func RegisterWorkflows(coordinator core.WorkflowCoordinator, protocol core.Proto
 			FailureBehavior: core.ContinueWorkflow,
 		},
 	})
+	if err != nil {
+		return err
+	}
 
-	return err
+	// Register the item import workflow
+	return coordinator.RegisterWorkflow(WorkflowImport, []core.OperationStep{
+		{
+			Operation:       fmt.Sprintf("%s.import", internal.PLUGIN_NAME),
+			Handler:         NewImportOperationHandler(protocol, ctx),
+			FailureBehavior: core.FailWorkflow,
+		},
+	})
 }
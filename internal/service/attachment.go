@@ -0,0 +1,78 @@
+package service
+
+import (
+	"go.lumeweb.com/portal-plugin-template/internal/db/models"
+	"go.uber.org/zap"
+)
+
+// ListAttachments retrieves the files attached to an item, oldest first
+// Returns the attachments, ErrNotFound if the item doesn't exist or the actor cannot see it,
+// or an error if the operation fails
+func (s *ItemServiceDefault) ListAttachments(actor *Actor, id uint64) ([]models.ItemAttachment, error) {
+	item, err := s.findItem(s.db, actor, id)
+	if err != nil {
+		return nil, err
+	}
+
+	attachments := []models.ItemAttachment{}
+	if err := s.db.Where("item_id = ?", item.ID).Order("id").Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// AttachmentUpload stores the file of a new attachment
+// The file is staged before the attachment is recorded and only stored once it is, so a
+// file is never stored for an attachment that could not be recorded.
+type AttachmentUpload interface {
+	// Stage receives the file and returns the attachment describing it
+	Stage() (*models.ItemAttachment, error)
+
+	// Store starts storing the staged file, returning the ID of the upload storing it
+	Store() (uint, error)
+
+	// Discard releases the staged file of an attachment that could not be recorded
+	Discard()
+}
+
+// AddAttachment attaches a file to an item the actor may modify
+// The permission check runs before the file is staged, so files are only uploaded for items
+// the actor may change. The attachment describing the file is linked to the item and the
+// actor, and recorded before the file is stored.
+// Returns the stored attachment, ErrNotFound if the item doesn't exist, ErrForbidden if it
+// is not owned by the actor, the error returned by upload, or an error if the operation fails
+func (s *ItemServiceDefault) AddAttachment(actor *Actor, id uint64, upload AttachmentUpload) (*models.ItemAttachment, error) {
+	item, err := s.findModifiableItem(s.db, actor, id)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment, err := upload.Stage()
+	if err != nil {
+		return nil, err
+	}
+
+	attachment.ItemID = item.ID
+	attachment.UploaderID = actor.UserID
+	if err := s.db.Create(attachment).Error; err != nil {
+		upload.Discard()
+		return nil, translateError(err)
+	}
+
+	uploadID, err := upload.Store()
+	if err != nil {
+		// The file won't be stored, so neither is the attachment
+		if err := s.db.Delete(attachment).Error; err != nil {
+			s.logger.Error("failed to remove attachment of a failed upload", zap.Uint("attachment_id", attachment.ID), zap.Error(err))
+		}
+		return nil, err
+	}
+
+	attachment.UploadID = uploadID
+	if err := s.db.Model(attachment).Update("upload_id", uploadID).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	return attachment, nil
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"fmt"
+
+	"go.lumeweb.com/portal-plugin-template/internal/db/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Batch operation kinds
+const (
+	BatchOpCreate = "create" // Create a new item
+	BatchOpUpdate = "update" // Replace the name, description, visibility and metadata of an item
+	BatchOpDelete = "delete" // Move an item to the trash
+)
+
+// BatchOperation describes a single operation within a batch
+type BatchOperation struct {
+	Op          string         // One of the BatchOp constants
+	ID          uint64         // Item to update or delete
+	Version     uint           // Expected item version for updates and deletes, 0 for any
+	Name        string         // Name for creates and updates
+	Description string         // Description for creates and updates
+	Visibility  string         // Visibility for creates and updates, empty for the default
+	Metadata    datatypes.JSON // Metadata for creates and updates, nil to leave it unset
+}
+
+// BatchResult is the outcome of a single batch operation
+type BatchResult struct {
+	Item *models.Item // The created or updated item, nil for deletes and failures
+	Err  error        // Why the operation failed, nil on success
+}
+
+// BatchError is returned when an atomic batch is rolled back because an operation failed
+type BatchError struct {
+	Index int   // Position of the failing operation in the batch
+	Err   error // Why the operation failed
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// ExecuteBatch runs a list of create, update and delete operations on behalf of the actor
+// In atomic mode all operations run in a single transaction and the first failure rolls
+// the whole batch back, returning a *BatchError. Otherwise every operation runs in its own
+// transaction and failures are reported in the results without stopping the batch.
+// Returns one result per operation, in order
+func (s *ItemServiceDefault) ExecuteBatch(actor *Actor, operations []BatchOperation, atomic bool) ([]BatchResult, error) {
+	if actor == nil {
+		return nil, ErrForbidden
+	}
+
+	results := make([]BatchResult, len(operations))
+
+	if atomic {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			for i := range operations {
+				item, err := s.runBatchOperation(tx, actor, &operations[i])
+				if err != nil {
+					return &BatchError{Index: i, Err: translateError(err)}
+				}
+				results[i].Item = item
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return results, nil
+	}
+
+	for i := range operations {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			item, err := s.runBatchOperation(tx, actor, &operations[i])
+			results[i].Item = item
+			return err
+		})
+		if err != nil {
+			results[i] = BatchResult{Err: translateError(err)}
+		}
+	}
+
+	return results, nil
+}
+
+// runBatchOperation executes a single batch operation within the given transaction
+func (s *ItemServiceDefault) runBatchOperation(tx *gorm.DB, actor *Actor, op *BatchOperation) (*models.Item, error) {
+	switch op.Op {
+	case BatchOpCreate:
+		return s.createItem(tx, actor, op.Name, op.Description, op.Visibility, op.Metadata)
+	case BatchOpUpdate:
+		updates, err := s.replacementUpdates(op.Name, op.Description, op.Visibility, op.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		return s.updateItem(tx, actor, op.ID, op.Version, updates)
+	case BatchOpDelete:
+		return nil, s.deleteItem(tx, actor, op.ID, op.Version)
+	default:
+		return nil, fmt.Errorf("%w: unknown batch operation %q", ErrValidation, op.Op)
+	}
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"go.lumeweb.com/portal-plugin-template/internal/db/models"
+	"gorm.io/gorm"
+)
+
+// cursor is the decoded form of an opaque keyset pagination cursor.
+// It points at the (sort field, id) key of an item on a page boundary.
+type cursor struct {
+	Sort     string `json:"s"`           // Sort field the cursor was issued for
+	Desc     bool   `json:"d,omitempty"` // Sort direction the cursor was issued for
+	Value    string `json:"v"`           // Sort field value of the boundary item
+	ID       uint   `json:"i"`           // ID of the boundary item, breaks ties on the sort field
+	Backward bool   `json:"b,omitempty"` // Whether the cursor points to the preceding page
+}
+
+// sortValue returns the value of the sort field for an item in cursor form
+func sortValue(sort sortSpec, item *models.Item) string {
+	switch sort.Field {
+	case "name":
+		return item.Name
+	case "updated_at":
+		return item.UpdatedAt.Format(time.RFC3339Nano)
+	default:
+		return item.CreatedAt.Format(time.RFC3339Nano)
+	}
+}
+
+// key returns the cursor's sort value converted to the column's type
+func (c *cursor) key() (any, error) {
+	if c.Sort == "name" {
+		return c.Value, nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, c.Value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return t, nil
+}
+
+// encodeCursor builds an opaque cursor for the given boundary item
+func encodeCursor(sort sortSpec, item *models.Item, backward bool) string {
+	data, _ := json.Marshal(cursor{
+		Sort:     sort.Field,
+		Desc:     sort.Desc,
+		Value:    sortValue(sort, item),
+		ID:       item.ID,
+		Backward: backward,
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses an opaque cursor produced by encodeCursor for the given sort
+func decodeCursor(sort sortSpec, value string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if c.Sort != sort.Field || c.Desc != sort.Desc {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+// paginateKeyset runs a keyset-paginated query over items matching the given scope,
+// ordered by the sort field with the item ID as a tie breaker. It fills in the next
+// and previous cursors on the pagination and never counts the matching rows.
+func (s *ItemServiceDefault) paginateKeyset(scope func(db *gorm.DB) *gorm.DB, sort sortSpec, pagination *Pagination) ([]models.Item, error) {
+	query := s.db.Scopes(scope, preloadTags)
+
+	var from *cursor
+	if pagination.Cursor != "" {
+		var err error
+		if from, err = decodeCursor(sort, pagination.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	backward := from != nil && from.Backward
+	if from != nil {
+		key, err := from.key()
+		if err != nil {
+			return nil, err
+		}
+
+		// Moving forward in ascending order, or backward in descending order,
+		// means looking for larger keys
+		op := ">"
+		if sort.Desc != backward {
+			op = "<"
+		}
+		query = query.Where(
+			sort.Column+" "+op+" ? OR ("+sort.Column+" = ? AND id "+op+" ?)",
+			key, key, from.ID,
+		)
+	}
+	query = query.Order(sort.orderClause(backward))
+
+	// Fetch one extra row to find out whether another page follows
+	var items []models.Item
+	if err := query.Limit(pagination.Limit + 1).Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	more := len(items) > pagination.Limit
+	if more {
+		items = items[:pagination.Limit]
+	}
+	if backward {
+		slices.Reverse(items)
+	}
+
+	pagination.NextCursor = ""
+	pagination.PrevCursor = ""
+	if len(items) == 0 {
+		return items, nil
+	}
+
+	first, last := &items[0], &items[len(items)-1]
+	if backward {
+		// We came from a later page, so there is always a next page
+		pagination.NextCursor = encodeCursor(sort, last, false)
+		if more {
+			pagination.PrevCursor = encodeCursor(sort, first, true)
+		}
+	} else {
+		if more {
+			pagination.NextCursor = encodeCursor(sort, last, false)
+		}
+		if from != nil {
+			pagination.PrevCursor = encodeCursor(sort, first, true)
+		}
+	}
+
+	return items, nil
+}
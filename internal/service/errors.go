@@ -0,0 +1,50 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Sentinel errors returned by the services. Callers should match them with
+// errors.Is, as they are usually wrapped with more specific detail.
+var (
+	// ErrNotFound is returned when the requested item does not exist or is not visible to the actor
+	ErrNotFound = errors.New("item not found")
+	// ErrConflict is returned when a change conflicts with the current state of an item
+	ErrConflict = errors.New("item conflict")
+	// ErrForbidden is returned when the acting user is not allowed to modify an item
+	ErrForbidden = errors.New("item is owned by another user")
+	// ErrValidation is returned when the supplied input is invalid
+	ErrValidation = errors.New("invalid input")
+	// ErrPreconditionFailed is returned when the caller's expected item version does not match
+	ErrPreconditionFailed = errors.New("item version mismatch")
+)
+
+var (
+	// ErrInvalidVisibility is returned when an unknown visibility level is supplied
+	ErrInvalidVisibility = fmt.Errorf("%w: invalid item visibility", ErrValidation)
+	// ErrInvalidFilter is returned when an item filter uses an unsupported sort field or direction
+	ErrInvalidFilter = fmt.Errorf("%w: invalid item filter", ErrValidation)
+	// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+	// or was issued for a different sort order
+	ErrInvalidCursor = fmt.Errorf("%w: invalid pagination cursor", ErrValidation)
+	// ErrRevisionNotFound is returned when an item has no revision with the requested number
+	ErrRevisionNotFound = fmt.Errorf("%w: no such revision", ErrNotFound)
+)
+
+// translateError maps database errors onto the service's sentinel errors,
+// leaving any other error untouched
+func translateError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey), errors.Is(err, gorm.ErrForeignKeyViolated):
+		return fmt.Errorf("%w: %w", ErrConflict, err)
+	default:
+		return err
+	}
+}
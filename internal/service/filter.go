@@ -0,0 +1,106 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Sort directions accepted by ItemFilter
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// sortColumns whitelists the fields items can be sorted by, mapped to their columns
+var sortColumns = map[string]string{
+	"name":       "name",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// ItemFilter defines sorting and filtering options for listing items
+// Zero values leave the corresponding option unset.
+type ItemFilter struct {
+	Sort          string            // Field to sort by: name, created_at or updated_at (default created_at)
+	Order         string            // Sort direction: asc or desc (default asc)
+	NamePrefix    string            // Only include items whose name starts with this prefix
+	CreatedAfter  *time.Time        // Only include items created after this time
+	CreatedBefore *time.Time        // Only include items created before this time
+	UpdatedAfter  *time.Time        // Only include items updated after this time
+	UpdatedBefore *time.Time        // Only include items updated before this time
+	Tags          []string          // Only include items carrying every one of these tags
+	Metadata      map[string]string // Only include items whose metadata holds each value at its key
+}
+
+// sortSpec is a validated sort order
+type sortSpec struct {
+	Field  string // Public name of the sort field
+	Column string // Database column backing the field
+	Desc   bool   // Whether to sort in descending order
+}
+
+// sortSpec validates the filter's sort options against the whitelist
+func (f *ItemFilter) sortSpec() (sortSpec, error) {
+	field, order := "created_at", SortAsc
+	if f != nil && f.Sort != "" {
+		field = f.Sort
+	}
+	if f != nil && f.Order != "" {
+		order = strings.ToLower(f.Order)
+	}
+
+	column, ok := sortColumns[field]
+	if !ok {
+		return sortSpec{}, fmt.Errorf("%w: cannot sort by %q", ErrInvalidFilter, field)
+	}
+	if order != SortAsc && order != SortDesc {
+		return sortSpec{}, fmt.Errorf("%w: unknown sort order %q", ErrInvalidFilter, order)
+	}
+
+	return sortSpec{Field: field, Column: column, Desc: order == SortDesc}, nil
+}
+
+// orderClause returns the ORDER BY clause for the sort, using the item ID as a tie breaker.
+// When reverse is set the direction is flipped.
+func (s sortSpec) orderClause(reverse bool) string {
+	dir := "ASC"
+	if s.Desc != reverse {
+		dir = "DESC"
+	}
+	return fmt.Sprintf("%s %s, id %s", s.Column, dir, dir)
+}
+
+// scope restricts a query to the items matching the filter
+// Columns are qualified so the scope can be combined with joins on other tables.
+func (f *ItemFilter) scope() func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if f == nil {
+			return db
+		}
+		if f.NamePrefix != "" {
+			db = db.Where("items.name LIKE ? ESCAPE '!'", escapeLike(f.NamePrefix)+"%")
+		}
+		if f.CreatedAfter != nil {
+			db = db.Where("items.created_at > ?", *f.CreatedAfter)
+		}
+		if f.CreatedBefore != nil {
+			db = db.Where("items.created_at < ?", *f.CreatedBefore)
+		}
+		if f.UpdatedAfter != nil {
+			db = db.Where("items.updated_at > ?", *f.UpdatedAfter)
+		}
+		if f.UpdatedBefore != nil {
+			db = db.Where("items.updated_at < ?", *f.UpdatedBefore)
+		}
+		db = scopeTagged(f.Tags)(db)
+		return scopeMetadata(f.Metadata)(db)
+	}
+}
+
+// escapeLike escapes LIKE wildcards using '!' as the escape character
+func escapeLike(value string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(value)
+}
@@ -1,29 +1,126 @@
 package service
 
 import (
+	"fmt"
+	"time"
+
 	"go.lumeweb.com/portal-plugin-template/internal/db/models"
+	"go.lumeweb.com/portal-plugin-template/internal/jsonschema"
 	"go.lumeweb.com/portal/core"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
 const ITEM_SERVICE = "item"
 
-// Pagination defines the structure for paginated requests
+// Pagination defines the structure for paginated requests.
+// Page based pagination is the legacy mode; setting UseCursor switches to
+// keyset pagination, which skips counting and stays stable while items are added.
 type Pagination struct {
-	Page  int // Current page number (1-based)
-	Limit int // Number of items per page
+	Page      int    // Current page number (1-based), page mode only
+	Limit     int    // Number of items per page
+	UseCursor bool   // Whether to paginate by cursor instead of by page
+	Cursor    string // Opaque cursor to continue from, empty for the first page
+
+	// Filled in by the service in cursor mode
+	NextCursor string // Cursor for the following page, empty on the last page
+	PrevCursor string // Cursor for the preceding page, empty on the first page
+}
+
+// Actor identifies the user performing an operation on items
+type Actor struct {
+	UserID uint // ID of the acting user
+	Admin  bool // Whether the user holds the admin role and bypasses ownership checks
+}
+
+// UserActor returns the actor for a user, flagging users holding the admin role.
+// Work done on behalf of a user outside of an API request, such as a workflow, acts
+// with the same role as the user's requests.
+func UserActor(ctx core.Context, userID uint) *Actor {
+	actor := &Actor{UserID: userID}
+
+	userSvc := core.GetService[core.UserService](ctx, core.USER_SERVICE)
+	if exists, user, err := userSvc.AccountExists(userID); err == nil && exists {
+		actor.Admin = user.Role == core.ACCESS_ADMIN_ROLE
+	}
+
+	return actor
+}
+
+// canModify reports whether the actor is allowed to modify the given item
+func (a *Actor) canModify(item *models.Item) bool {
+	return a != nil && (a.Admin || item.OwnerID == a.UserID)
+}
+
+// canView reports whether the actor is allowed to read the given item
+// Private items are only readable by their owner and admins
+func (a *Actor) canView(item *models.Item) bool {
+	return item.Visibility != models.VisibilityPrivate || a.canModify(item)
+}
+
+// scopeListable restricts a query to the items the actor may see in listings.
+// Anonymous callers only see public items, users see their own items and
+// admins see everything.
+func scopeListable(actor *Actor) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		switch {
+		case actor == nil:
+			return db.Where("visibility = ?", models.VisibilityPublic)
+		case actor.Admin:
+			return db
+		default:
+			return db.Where("owner_id = ?", actor.UserID)
+		}
+	}
+}
+
+// scopePrivate restricts a query to the actor's own private items
+func scopePrivate(actor *Actor) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("owner_id = ? AND visibility = ?", actor.UserID, models.VisibilityPrivate)
+	}
+}
+
+// ItemPatch describes a partial update of an item
+// Nil fields are left unchanged. An empty visibility resets the item to public.
+type ItemPatch struct {
+	Name        *string         // New name, must not be empty when set
+	Description *string         // New description, empty to clear it
+	Visibility  *string         // New visibility, empty for the default
+	Metadata    *datatypes.JSON // JSON Merge Patch applied to the metadata, empty to clear it
 }
 
 // ItemService defines the interface for managing items in the system.
 // It provides methods for CRUD operations and search functionality.
 type ItemService interface {
 	core.Service
-	ListItems(pagination *Pagination) ([]models.Item, int64, error)
-	CreateItem(name string, description string) (*models.Item, error)
-	GetItem(id uint64) (*models.Item, error)
-	UpdateItem(id uint64, name string, description string) error
-	DeleteItem(id uint64) error
-	SearchItems(query string, limit int) ([]models.Item, int64, error)
+	ListItems(actor *Actor, filter *ItemFilter, pagination *Pagination) ([]models.Item, int64, error)
+	ListPrivateItems(actor *Actor, pagination *Pagination) ([]models.Item, int64, error)
+	CreateItem(actor *Actor, name string, description string, visibility string, metadata datatypes.JSON) (*models.Item, error)
+	GetItem(actor *Actor, id uint64) (*models.Item, error)
+	UpdateItem(actor *Actor, id uint64, version uint, name string, description string, visibility string, metadata datatypes.JSON) (*models.Item, error)
+	PatchItem(actor *Actor, id uint64, version uint, patch *ItemPatch) (*models.Item, error)
+	DeleteItem(actor *Actor, id uint64, version uint) error
+	ListTrash(actor *Actor, filter *ItemFilter, pagination *Pagination) ([]models.Item, int64, error)
+	RestoreItem(actor *Actor, id uint64, version uint) (*models.Item, error)
+	PurgeItem(actor *Actor, id uint64, version uint) error
+	PurgeExpiredItems(cutoff time.Time) (int64, error)
+	ListRevisions(actor *Actor, id uint64, pagination *Pagination) ([]models.ItemRevision, int64, error)
+	GetRevision(actor *Actor, id uint64, revision uint) (*RevisionDiff, error)
+	RevertItem(actor *Actor, id uint64, revision uint, version uint) (*models.Item, error)
+	ExecuteBatch(actor *Actor, operations []BatchOperation, atomic bool) ([]BatchResult, error)
+	ExportItems(actor *Actor, filter *ItemFilter, fn func(item *models.Item) error) error
+	SearchItems(query string, tags []string, pagination *Pagination) ([]SearchResult, int64, error)
+	GetQuota(actor *Actor) (*Quota, error)
+	ListTags(category string) ([]models.Tag, error)
+	CreateTag(actor *Actor, name string, category string) (*models.Tag, error)
+	UpdateTag(actor *Actor, id uint64, name string, category string) (*models.Tag, error)
+	DeleteTag(actor *Actor, id uint64) error
+	SetItemTags(actor *Actor, id uint64, version uint, names []string) (*models.Item, error)
+	CountTags(actor *Actor, filter *ItemFilter, category string) ([]TagCount, error)
+	ListAttachments(actor *Actor, id uint64) ([]models.ItemAttachment, error)
+	AddAttachment(actor *Actor, id uint64, upload AttachmentUpload) (*models.ItemAttachment, error)
+	CanReadObject(actor *Actor, hash string) (bool, error)
 }
 
 // Verify ItemServiceDefault implements ItemService interface
@@ -31,9 +128,11 @@ var _ ItemService = (*ItemServiceDefault)(nil)
 
 // ItemServiceDefault provides the default implementation of ItemService
 type ItemServiceDefault struct {
-	ctx    core.Context
-	db     *gorm.DB
-	logger *core.Logger
+	ctx            core.Context
+	db             *gorm.DB
+	logger         *core.Logger
+	search         SearchIndex
+	metadataSchema *jsonschema.Schema
 }
 
 func NewItemService() (core.Service, []core.ContextBuilderOption, error) {
@@ -44,6 +143,16 @@ func NewItemService() (core.Service, []core.ContextBuilderOption, error) {
 			service.ctx = ctx
 			service.db = ctx.DB()
 			service.logger = ctx.ServiceLogger(service)
+			service.search = NewSearchIndex(service.db.Dialector.Name(), service.db)
+
+			schema, err := service.loadMetadataSchema()
+			if err != nil {
+				return err
+			}
+			service.metadataSchema = schema
+
+			// Permanently remove items once they have been in the trash too long
+			go service.runPurge(ctx)
 			return nil
 		}),
 	), nil
@@ -53,33 +162,113 @@ func (s *ItemServiceDefault) ID() string {
 	return ITEM_SERVICE
 }
 
-// ListItems retrieves a paginated list of items
-// Returns the items for the requested page, total count of all items, and any error
-func (s *ItemServiceDefault) ListItems(pagination *Pagination) ([]models.Item, int64, error) {
+// ListItems retrieves a sorted, filtered and paginated list of items
+// A nil actor lists public items only, otherwise the list is scoped to items owned
+// by the actor unless the actor is an admin. A nil filter uses the default sort order.
+// Returns the items for the requested page, total count of all matching items, and any error
+func (s *ItemServiceDefault) ListItems(actor *Actor, filter *ItemFilter, pagination *Pagination) ([]models.Item, int64, error) {
+	return s.paginate(scopeListable(actor), filter, pagination)
+}
+
+// ListPrivateItems retrieves a paginated list of the actor's private items
+// Returns the items for the requested page, total count of the actor's private items, and any error
+func (s *ItemServiceDefault) ListPrivateItems(actor *Actor, pagination *Pagination) ([]models.Item, int64, error) {
+	if actor == nil {
+		return nil, 0, ErrForbidden
+	}
+
+	return s.paginate(scopePrivate(actor), nil, pagination)
+}
+
+// paginate runs a sorted, paginated query over items matching the given scope and filter
+// In cursor mode the returned total is always zero as the rows are not counted.
+func (s *ItemServiceDefault) paginate(scope func(db *gorm.DB) *gorm.DB, filter *ItemFilter, pagination *Pagination) ([]models.Item, int64, error) {
+	sort, err := filter.sortSpec()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	scopes := []func(db *gorm.DB) *gorm.DB{scope, filter.scope()}
+
+	if pagination.UseCursor {
+		items, err := s.paginateKeyset(func(db *gorm.DB) *gorm.DB { return db.Scopes(scopes...) }, sort, pagination)
+		return items, 0, err
+	}
+
 	var items []models.Item
 	var total int64
 
-	if err := s.db.Model(&models.Item{}).Count(&total).Error; err != nil {
+	if err := s.db.Model(&models.Item{}).Scopes(scopes...).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	offset := (pagination.Page - 1) * pagination.Limit
-	if err := s.db.Offset(offset).Limit(pagination.Limit).Find(&items).Error; err != nil {
+	if err := s.db.Scopes(append(scopes, preloadTags)...).Order(sort.orderClause(false)).Offset(offset).Limit(pagination.Limit).Find(&items).Error; err != nil {
 		return nil, 0, err
 	}
 
 	return items, total, nil
 }
 
-// CreateItem creates a new item with the given name and description
-// Returns the created item or an error if the operation fails
-func (s *ItemServiceDefault) CreateItem(name string, description string) (*models.Item, error) {
+// CreateItem creates a new item with the given name and description, owned by the actor
+// An empty visibility defaults to public and empty metadata leaves the item without metadata.
+// Returns the created item, ErrValidation if the values are invalid, a *MetadataError if the
+// metadata is rejected, a *QuotaError if an item quota is exhausted, or an error if the
+// operation fails
+func (s *ItemServiceDefault) CreateItem(actor *Actor, name string, description string, visibility string, metadata datatypes.JSON) (*models.Item, error) {
+	var item *models.Item
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		item, err = s.createItem(tx, actor, name, description, visibility, metadata)
+		return err
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return item, nil
+}
+
+// createItem validates and creates an item within the given transaction
+func (s *ItemServiceDefault) createItem(tx *gorm.DB, actor *Actor, name string, description string, visibility string, metadata datatypes.JSON) (*models.Item, error) {
+	if actor == nil {
+		return nil, ErrForbidden
+	}
+
+	if visibility == "" {
+		visibility = models.VisibilityPublic
+	}
+	if !models.ValidVisibility(visibility) {
+		return nil, ErrInvalidVisibility
+	}
+	if err := s.validateName(name); err != nil {
+		return nil, err
+	}
+	if err := s.validateDescription(description); err != nil {
+		return nil, err
+	}
+	metadata, err := s.normalizeMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+
 	item := &models.Item{
+		OwnerID:     actor.UserID,
 		Name:        name,
 		Description: description,
+		Visibility:  visibility,
+		Version:     1,
+		Metadata:    metadata,
 	}
 
-	if err := s.db.Create(item).Error; err != nil {
+	if err := s.checkQuota(tx, actor); err != nil {
+		return nil, err
+	}
+	if err := tx.Create(item).Error; err != nil {
+		return nil, err
+	}
+	if err := recordRevision(tx, actor, item, models.RevisionActionCreate); err != nil {
 		return nil, err
 	}
 
@@ -87,56 +276,272 @@ func (s *ItemServiceDefault) CreateItem(name string, description string) (*model
 }
 
 // GetItem retrieves a single item by its ID
-// Returns the item if found, or an error if not found or operation fails
-func (s *ItemServiceDefault) GetItem(id uint64) (*models.Item, error) {
+// Private items are reported as not found unless the actor owns them or is an admin.
+// Returns the item if found, ErrNotFound if it does not exist, or an error if the operation fails
+func (s *ItemServiceDefault) GetItem(actor *Actor, id uint64) (*models.Item, error) {
+	return s.findItem(s.db, actor, id)
+}
+
+// findItem loads an item the actor is allowed to see using the given connection
+func (s *ItemServiceDefault) findItem(db *gorm.DB, actor *Actor, id uint64) (*models.Item, error) {
 	var item models.Item
-	if err := s.db.First(&item, id).Error; err != nil {
-		return nil, err
+	if err := db.Scopes(preloadTags).First(&item, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	if !actor.canView(&item) {
+		return nil, ErrNotFound
 	}
+
 	return &item, nil
 }
 
+// findModifiableItem loads an item and checks that the actor is allowed to modify it
+func (s *ItemServiceDefault) findModifiableItem(db *gorm.DB, actor *Actor, id uint64) (*models.Item, error) {
+	item, err := s.findItem(db, actor, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !actor.canModify(item) {
+		return nil, ErrForbidden
+	}
+
+	return item, nil
+}
+
 // UpdateItem updates an existing item with new values
-// Returns an error if the item doesn't exist or the update fails
-func (s *ItemServiceDefault) UpdateItem(id uint64, name string, description string) error {
-	var item models.Item
-	if err := s.db.First(&item, id).Error; err != nil {
+// An empty visibility keeps the item's current visibility and nil metadata keeps the current
+// metadata, while a JSON null removes it. A non-zero version must match the item's current
+// version for the update to be applied.
+// Returns the updated item, ErrNotFound if the item doesn't exist, ErrForbidden if it is not
+// owned by the actor, ErrValidation if the new values are invalid, a *MetadataError if the
+// metadata is rejected, ErrPreconditionFailed if the version does not match, ErrConflict if
+// the item changed concurrently, or an error if the update fails
+func (s *ItemServiceDefault) UpdateItem(actor *Actor, id uint64, version uint, name string, description string, visibility string, metadata datatypes.JSON) (*models.Item, error) {
+	updates, err := s.replacementUpdates(name, description, visibility, metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.applyUpdates(actor, id, version, updates)
+}
+
+// replacementUpdates validates the values of a full update and returns the column updates
+// An empty visibility and nil metadata are left out so the item keeps its current values.
+func (s *ItemServiceDefault) replacementUpdates(name string, description string, visibility string, metadata datatypes.JSON) (map[string]any, error) {
+	if visibility != "" && !models.ValidVisibility(visibility) {
+		return nil, ErrInvalidVisibility
+	}
+	if err := s.validateName(name); err != nil {
+		return nil, err
+	}
+	if err := s.validateDescription(description); err != nil {
+		return nil, err
+	}
+
+	updates := map[string]any{
+		"name":        name,
+		"description": description,
+	}
+	if visibility != "" {
+		updates["visibility"] = visibility
+	}
+	if metadata != nil {
+		normalized, err := s.normalizeMetadata(metadata)
+		if err != nil {
+			return nil, err
+		}
+		updates["metadata"] = normalized
+	}
+
+	return updates, nil
+}
+
+// PatchItem applies a partial update to an existing item, writing only the supplied fields
+// Metadata is merged into the item's current metadata as a JSON Merge Patch.
+// A non-zero version must match the item's current version for the patch to be applied.
+// Returns the updated item, ErrNotFound if the item doesn't exist, ErrForbidden if it is not
+// owned by the actor, ErrValidation if the new values are invalid, a *MetadataError if the
+// merged metadata is rejected, ErrPreconditionFailed if the version does not match,
+// ErrConflict if the item changed concurrently, or an error if the update fails
+func (s *ItemServiceDefault) PatchItem(actor *Actor, id uint64, version uint, patch *ItemPatch) (*models.Item, error) {
+	updates := map[string]any{}
+
+	if patch.Name != nil {
+		if err := s.validateName(*patch.Name); err != nil {
+			return nil, err
+		}
+		updates["name"] = *patch.Name
+	}
+
+	if patch.Description != nil {
+		if err := s.validateDescription(*patch.Description); err != nil {
+			return nil, err
+		}
+		updates["description"] = *patch.Description
+	}
+
+	if patch.Visibility != nil {
+		visibility := *patch.Visibility
+		if visibility == "" {
+			visibility = models.VisibilityPublic
+		}
+		if !models.ValidVisibility(visibility) {
+			return nil, ErrInvalidVisibility
+		}
+		updates["visibility"] = visibility
+	}
+
+	if patch.Metadata != nil {
+		if len(*patch.Metadata) == 0 {
+			updates["metadata"] = datatypes.JSON(nil)
+		} else {
+			// Merged with the current metadata once the item is loaded
+			updates["metadata"] = metadataPatch(*patch.Metadata)
+		}
+	}
+
+	return s.applyUpdates(actor, id, version, updates)
+}
+
+// applyUpdates writes the given column updates to an item the actor may modify
+// and records the change in the item's revision history.
+func (s *ItemServiceDefault) applyUpdates(actor *Actor, id uint64, version uint, updates map[string]any) (*models.Item, error) {
+	var updated *models.Item
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		updated, err = s.updateItem(tx, actor, id, version, updates)
+		return err
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return updated, nil
+}
+
+// updateItem writes column updates to an item the actor may modify within the given transaction
+func (s *ItemServiceDefault) updateItem(tx *gorm.DB, actor *Actor, id uint64, version uint, updates map[string]any) (*models.Item, error) {
+	item, err := s.findModifiableItem(tx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkVersion(item, version); err != nil {
+		return nil, err
+	}
+
+	if len(updates) == 0 {
+		return item, nil
+	}
+
+	if patch, ok := updates["metadata"].(metadataPatch); ok {
+		merged, err := mergeMetadata(item.Metadata, datatypes.JSON(patch))
+		if err != nil {
+			return nil, err
+		}
+		if updates["metadata"], err = s.normalizeMetadata(merged); err != nil {
+			return nil, err
+		}
+	}
+
+	return writeItem(tx, actor, item, version, updates, models.RevisionActionUpdate)
+}
+
+// DeleteItem moves an item to the trash
+// Deleted items can be restored with RestoreItem until they are purged. A non-zero
+// version must match the item's current version for the item to be deleted.
+// Returns ErrNotFound if the item doesn't exist, ErrForbidden if it is not owned by the actor,
+// ErrPreconditionFailed if the version does not match, ErrConflict if the item changed
+// concurrently, or an error if the deletion fails
+func (s *ItemServiceDefault) DeleteItem(actor *Actor, id uint64, version uint) error {
+	return translateError(s.db.Transaction(func(tx *gorm.DB) error {
+		return s.deleteItem(tx, actor, id, version)
+	}))
+}
+
+// deleteItem moves an item the actor may modify to the trash within the given transaction
+func (s *ItemServiceDefault) deleteItem(tx *gorm.DB, actor *Actor, id uint64, version uint) error {
+	item, err := s.findModifiableItem(tx, actor, id)
+	if err != nil {
 		return err
 	}
 
-	item.Name = name
-	item.Description = description
+	if err := checkVersion(item, version); err != nil {
+		return err
+	}
 
-	return s.db.Save(&item).Error
+	_, err = writeItem(tx, actor, item, version, map[string]any{"deleted_at": time.Now()}, models.RevisionActionDelete)
+	return err
 }
 
-// DeleteItem removes an item from the database
-// Returns an error if the item doesn't exist or the deletion fails
-func (s *ItemServiceDefault) DeleteItem(id uint64) error {
-	return s.db.Delete(&models.Item{}, id).Error
+// writeItem applies column updates to an item, conditional on the version it was read at,
+// and records the resulting state as a new revision. The write is conditional so a
+// concurrent change between reading and writing is reported rather than silently
+// overwritten. Returns the item as stored after the change.
+func writeItem(tx *gorm.DB, actor *Actor, item *models.Item, version uint, updates map[string]any, action string) (*models.Item, error) {
+	updates["version"] = gorm.Expr("version + 1")
+
+	result := tx.Unscoped().Model(&models.Item{}).
+		Where("id = ? AND version = ?", item.ID, item.Version).
+		Updates(updates)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, versionMismatch(version)
+	}
+
+	// Reload so the revision and the response reflect exactly what was stored
+	var updated models.Item
+	if err := tx.Unscoped().Scopes(preloadTags).First(&updated, item.ID).Error; err != nil {
+		return nil, err
+	}
+
+	if err := recordRevision(tx, actor, &updated, action); err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
 }
 
-// SearchItems performs a text search on item names and descriptions
-// Returns matching items up to the specified limit, total count of matches, and any error
-func (s *ItemServiceDefault) SearchItems(query string, limit int) ([]models.Item, int64, error) {
-	var items []models.Item
-	var total int64
+// checkVersion returns ErrPreconditionFailed if an expected version was given
+// and the item is at a different version
+func checkVersion(item *models.Item, version uint) error {
+	if version != 0 && item.Version != version {
+		return fmt.Errorf("%w: item is at version %d", ErrPreconditionFailed, item.Version)
+	}
+	return nil
+}
 
-	searchQuery := "%" + query + "%"
+// versionMismatch returns the error for a conditional write that matched no rows.
+// Callers that stated an expected version get ErrPreconditionFailed, others ErrConflict.
+func versionMismatch(version uint) error {
+	if version != 0 {
+		return fmt.Errorf("%w: item was modified concurrently", ErrPreconditionFailed)
+	}
+	return fmt.Errorf("%w: item was modified concurrently", ErrConflict)
+}
 
-	if err := s.db.Model(&models.Item{}).Where(
-		"name LIKE ? OR description LIKE ?",
-		searchQuery, searchQuery,
-	).Count(&total).Error; err != nil {
+// SearchItems performs a ranked full-text search on the names and descriptions of public items
+// Every word in the query is matched as a prefix. Non-empty tags restrict the search to items
+// carrying every one of them.
+// Returns the matching items for the requested page, total count of matches, and any error
+func (s *ItemServiceDefault) SearchItems(query string, tags []string, pagination *Pagination) ([]SearchResult, int64, error) {
+	results, total, err := s.search.Search(query, tags, pagination)
+	if err != nil {
 		return nil, 0, err
 	}
 
-	if err := s.db.Where(
-		"name LIKE ? OR description LIKE ?",
-		searchQuery, searchQuery,
-	).Limit(limit).Find(&items).Error; err != nil {
+	items := make([]*models.Item, 0, len(results))
+	for i := range results {
+		items = append(items, &results[i].Item)
+	}
+	if err := s.attachTags(items); err != nil {
 		return nil, 0, err
 	}
 
-	return items, total, nil
+	return results, total, nil
 }
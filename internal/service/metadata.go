@@ -0,0 +1,212 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.lumeweb.com/portal-plugin-template/internal"
+	pluginConfig "go.lumeweb.com/portal-plugin-template/internal/config"
+	"go.lumeweb.com/portal-plugin-template/internal/jsonschema"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidMetadata is returned when item metadata is not a valid JSON object
+// or does not satisfy the configured schema
+var ErrInvalidMetadata = fmt.Errorf("%w: invalid item metadata", ErrValidation)
+
+// MetadataError is returned when item metadata is rejected
+// It unwraps to ErrInvalidMetadata.
+type MetadataError struct {
+	Fields map[string]string // Failures keyed by the dotted path of the invalid value, empty for the whole document
+}
+
+func (e *MetadataError) Error() string {
+	paths := make([]string, 0, len(e.Fields))
+	for path := range e.Fields {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	parts := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if path == "" {
+			parts = append(parts, e.Fields[path])
+		} else {
+			parts = append(parts, path+" "+e.Fields[path])
+		}
+	}
+
+	return fmt.Sprintf("%v: %s", ErrInvalidMetadata, strings.Join(parts, ", "))
+}
+
+func (e *MetadataError) Unwrap() error {
+	return ErrInvalidMetadata
+}
+
+// metadataKeyPattern matches a metadata key: object keys joined with dots
+var metadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$`)
+
+// ValidMetadataKey reports whether key can be used to filter items on metadata.
+// Keys consist of letters, digits, underscores and hyphens, with dots selecting nested keys.
+func ValidMetadataKey(key string) bool {
+	return len(key) <= 255 && metadataKeyPattern.MatchString(key)
+}
+
+// metadataPath converts a validated metadata key into a JSON path, such as $."a"."b"
+func metadataPath(key string) string {
+	return `$."` + strings.ReplaceAll(key, ".", `"."`) + `"`
+}
+
+// scopeMetadata restricts a query to items whose metadata holds the given value at every key.
+// Values are compared as text, so numbers match their JSON representation and booleans
+// match true or false.
+func scopeMetadata(metadata map[string]string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		// Sorted so the generated SQL is stable
+		keys := make([]string, 0, len(metadata))
+		for key := range metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			path := metadataPath(key)
+
+			switch db.Dialector.Name() {
+			case "mysql":
+				db = db.Where("JSON_UNQUOTE(JSON_EXTRACT(items.metadata, ?)) = ?", path, metadata[key])
+			default:
+				// SQLite extracts booleans as 1 and 0, so spell them out like MySQL does
+				db = db.Where(
+					"(CASE json_type(items.metadata, ?) WHEN 'true' THEN 'true' WHEN 'false' THEN 'false' ELSE CAST(json_extract(items.metadata, ?) AS TEXT) END) = ?",
+					path, path, metadata[key],
+				)
+			}
+		}
+		return db
+	}
+}
+
+// loadMetadataSchema parses the metadata schema configured in APIConfig.MetadataSchema
+// Returns nil when no schema is configured.
+func (s *ItemServiceDefault) loadMetadataSchema() (*jsonschema.Schema, error) {
+	apiCfg, ok := s.ctx.Config().GetAPI(internal.PLUGIN_NAME).(*pluginConfig.APIConfig)
+	if !ok || apiCfg.MetadataSchema == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(apiCfg.MetadataSchema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata schema: %w", err)
+	}
+
+	schema, err := jsonschema.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load metadata schema %s: %w", apiCfg.MetadataSchema, err)
+	}
+
+	return schema, nil
+}
+
+// maxMetadataSize returns the configured metadata size limit, 0 for unlimited
+func (s *ItemServiceDefault) maxMetadataSize() int {
+	if apiCfg, ok := s.ctx.Config().GetAPI(internal.PLUGIN_NAME).(*pluginConfig.APIConfig); ok {
+		return apiCfg.MaxMetadataSize
+	}
+	return 0
+}
+
+// normalizeMetadata validates item metadata and returns it in compact form
+// Empty metadata and a JSON null mean the item has no metadata and yield nil. Anything else
+// must be a JSON object within the size limit that satisfies the configured schema.
+// Returns a *MetadataError if the metadata is rejected
+func (s *ItemServiceDefault) normalizeMetadata(metadata datatypes.JSON) (datatypes.JSON, error) {
+	trimmed := bytes.TrimSpace(metadata)
+	if len(trimmed) == 0 || string(trimmed) == "null" {
+		return nil, nil
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, trimmed); err != nil {
+		return nil, &MetadataError{Fields: map[string]string{"": "must be valid JSON"}}
+	}
+	if compact.Bytes()[0] != '{' {
+		return nil, &MetadataError{Fields: map[string]string{"": "must be an object"}}
+	}
+
+	if limit := s.maxMetadataSize(); limit > 0 && compact.Len() > limit {
+		return nil, &MetadataError{Fields: map[string]string{"": fmt.Sprintf("must be at most %d bytes", limit)}}
+	}
+
+	if s.metadataSchema != nil {
+		if errs := s.metadataSchema.Validate(compact.Bytes()); len(errs) > 0 {
+			return nil, &MetadataError{Fields: errs}
+		}
+	}
+
+	return datatypes.JSON(compact.Bytes()), nil
+}
+
+// metadataPatch is a JSON Merge Patch (RFC 7396) for an item's metadata.
+// It is resolved against the item's current metadata when the update is applied.
+type metadataPatch datatypes.JSON
+
+// mergeMetadata applies a JSON Merge Patch (RFC 7396) to the current metadata
+// A patch that is not an object replaces the metadata entirely.
+func mergeMetadata(current datatypes.JSON, patch datatypes.JSON) (datatypes.JSON, error) {
+	var patchValue any
+	if err := decodeMetadata(patch, &patchValue); err != nil {
+		return nil, &MetadataError{Fields: map[string]string{"": "must be valid JSON"}}
+	}
+
+	var currentValue any
+	if len(current) > 0 {
+		if err := decodeMetadata(current, &currentValue); err != nil {
+			return nil, err
+		}
+	}
+
+	merged, err := json.Marshal(mergePatch(currentValue, patchValue))
+	if err != nil {
+		return nil, err
+	}
+
+	return merged, nil
+}
+
+// mergePatch implements the MergePatch algorithm of RFC 7396
+func mergePatch(target any, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = map[string]any{}
+	}
+
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+
+	return targetObj
+}
+
+// decodeMetadata unmarshals metadata keeping numbers as json.Number, so values
+// survive a merge without losing precision
+func decodeMetadata(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
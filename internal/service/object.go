@@ -0,0 +1,35 @@
+package service
+
+import (
+	"go.lumeweb.com/portal-plugin-template/internal/db/models"
+)
+
+// CanReadObject reports whether the actor may download the stored object with the given hash
+// Admins may read every object. Other actors may read objects they attached to an item, and
+// objects attached to an item they can see, so public attachments are readable anonymously.
+// The hash is in the base58 multihash form stored on attachments.
+func (s *ItemServiceDefault) CanReadObject(actor *Actor, hash string) (bool, error) {
+	if actor != nil && actor.Admin {
+		return true, nil
+	}
+
+	query := s.db.Model(&models.ItemAttachment{}).
+		Joins("JOIN items ON items.id = item_attachments.item_id AND items.deleted_at IS NULL").
+		Where("item_attachments.hash = ?", hash)
+
+	if actor == nil {
+		query = query.Where("items.visibility <> ?", models.VisibilityPrivate)
+	} else {
+		query = query.Where(
+			"items.visibility <> ? OR items.owner_id = ? OR item_attachments.uploader_id = ?",
+			models.VisibilityPrivate, actor.UserID, actor.UserID,
+		)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"fmt"
+
+	"go.lumeweb.com/portal-plugin-template/internal"
+	pluginConfig "go.lumeweb.com/portal-plugin-template/internal/config"
+	"go.lumeweb.com/portal-plugin-template/internal/db/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Quota scopes reported by QuotaError
+const (
+	QuotaScopeGlobal = "global" // The portal wide Config.MaxItems limit
+	QuotaScopeUser   = "user"   // The per-user APIConfig.UserMaxItems limit
+)
+
+// QuotaError is returned when creating an item would exceed an item quota
+type QuotaError struct {
+	Scope string // Which quota was exceeded, QuotaScopeGlobal or QuotaScopeUser
+	Usage int64  // Number of items counted against the quota
+	Limit int64  // The quota's limit
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s item quota exceeded: %d of %d items used", e.Scope, e.Usage, e.Limit)
+}
+
+// Quota reports a user's item usage against the configured limits
+// A limit of zero means unlimited.
+type Quota struct {
+	Usage       int64 // Items owned by the user
+	Limit       int64 // Per-user item limit
+	GlobalUsage int64 // Items stored across all users
+	GlobalLimit int64 // Portal wide item limit
+}
+
+// quotaLimits returns the configured global and per-user item limits
+func (s *ItemServiceDefault) quotaLimits() (global int64, perUser int64) {
+	cfg := s.ctx.Config()
+
+	if protoCfg, ok := cfg.GetProtocol(internal.PLUGIN_NAME).(*pluginConfig.Config); ok {
+		global = int64(protoCfg.MaxItems)
+	}
+	if apiCfg, ok := cfg.GetAPI(internal.PLUGIN_NAME).(*pluginConfig.APIConfig); ok {
+		perUser = int64(apiCfg.UserMaxItems)
+	}
+
+	return global, perUser
+}
+
+// quota counts the items relevant to the actor's quota using the given connection
+func (s *ItemServiceDefault) quota(db *gorm.DB, actor *Actor) (*Quota, error) {
+	quota := &Quota{}
+	quota.GlobalLimit, quota.Limit = s.quotaLimits()
+
+	if err := db.Model(&models.Item{}).Count(&quota.GlobalUsage).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.Model(&models.Item{}).Where("owner_id = ?", actor.UserID).Count(&quota.Usage).Error; err != nil {
+		return nil, err
+	}
+
+	return quota, nil
+}
+
+// lockQuota locks the quotas the actor's items count against until the transaction ends,
+// so concurrent transactions creating items check their quota one after another.
+// The portal wide lock is taken before the per-user one, so transactions can't deadlock.
+// SQLite has no row locks, but the insert makes the transaction a writer, which SQLite
+// serializes.
+func lockQuota(tx *gorm.DB, actor *Actor, global int64, perUser int64) error {
+	var owners []uint
+	if global > 0 {
+		owners = append(owners, 0)
+	}
+	if !actor.Admin && perUser > 0 {
+		owners = append(owners, actor.UserID)
+	}
+
+	for _, owner := range owners {
+		lock := models.QuotaLock{OwnerID: owner}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&lock).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).Where("owner_id = ?", owner).First(&lock).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkQuota returns a QuotaError if the actor cannot create another item.
+// Admins are exempt from the per-user quota but not from the global limit.
+// The quotas stay locked until tx ends, so the item must be created within tx.
+func (s *ItemServiceDefault) checkQuota(tx *gorm.DB, actor *Actor) error {
+	global, perUser := s.quotaLimits()
+	if err := lockQuota(tx, actor, global, perUser); err != nil {
+		return err
+	}
+
+	quota, err := s.quota(tx, actor)
+	if err != nil {
+		return err
+	}
+
+	if quota.GlobalLimit > 0 && quota.GlobalUsage >= quota.GlobalLimit {
+		return &QuotaError{Scope: QuotaScopeGlobal, Usage: quota.GlobalUsage, Limit: quota.GlobalLimit}
+	}
+
+	if !actor.Admin && quota.Limit > 0 && quota.Usage >= quota.Limit {
+		return &QuotaError{Scope: QuotaScopeUser, Usage: quota.Usage, Limit: quota.Limit}
+	}
+
+	return nil
+}
+
+// GetQuota returns the actor's item usage and the configured limits
+func (s *ItemServiceDefault) GetQuota(actor *Actor) (*Quota, error) {
+	if actor == nil {
+		return nil, ErrForbidden
+	}
+
+	return s.quota(s.db, actor)
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.lumeweb.com/portal-plugin-template/internal/db/models"
+	"gorm.io/gorm"
+)
+
+// FieldChange describes how a single item field differs between two revisions
+type FieldChange struct {
+	Field string // Name of the changed field
+	From  string // Value before the change, empty for the first revision
+	To    string // Value after the change
+}
+
+// RevisionDiff is a revision together with the changes it made to the revision before it
+type RevisionDiff struct {
+	models.ItemRevision
+	Changes []FieldChange // Fields that differ from the previous revision
+}
+
+// recordRevision stores a snapshot of the item's current state as a new revision
+// It must be called within the transaction that made the change, with the item's tags loaded.
+func recordRevision(tx *gorm.DB, actor *Actor, item *models.Item, action string) error {
+	names := make([]string, 0, len(item.Tags))
+	for _, tag := range item.Tags {
+		names = append(names, tag.Name)
+	}
+	tags, err := json.Marshal(names)
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&models.ItemRevision{
+		ItemID:      item.ID,
+		Revision:    item.Version,
+		Action:      action,
+		ActorID:     actor.UserID,
+		Name:        item.Name,
+		Description: item.Description,
+		Visibility:  item.Visibility,
+		Metadata:    item.Metadata,
+		Tags:        tags,
+	}).Error
+}
+
+// diffRevisions lists the fields that differ between two revisions
+// A nil previous revision is treated as an empty item.
+func diffRevisions(prev *models.ItemRevision, cur *models.ItemRevision) []FieldChange {
+	if prev == nil {
+		prev = &models.ItemRevision{}
+	}
+
+	fields := []struct {
+		Name     string
+		From, To string
+	}{
+		{"name", prev.Name, cur.Name},
+		{"description", prev.Description, cur.Description},
+		{"visibility", prev.Visibility, cur.Visibility},
+		{"metadata", string(prev.Metadata), string(cur.Metadata)},
+		{"tags", string(prev.Tags), string(cur.Tags)},
+	}
+
+	changes := make([]FieldChange, 0, len(fields))
+	for _, f := range fields {
+		if f.From != f.To {
+			changes = append(changes, FieldChange{Field: f.Name, From: f.From, To: f.To})
+		}
+	}
+
+	return changes
+}
+
+// findHistoryItem loads an item whose history the actor may read
+// Deleted items are included, but only for actors that may modify them.
+func (s *ItemServiceDefault) findHistoryItem(db *gorm.DB, actor *Actor, id uint64) (*models.Item, error) {
+	item, err := s.findItem(db.Unscoped(), actor, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if item.DeletedAt.Valid && !actor.canModify(item) {
+		return nil, ErrNotFound
+	}
+
+	return item, nil
+}
+
+// findRevision loads a single revision of an item using the given connection
+func findRevision(db *gorm.DB, itemID uint, revision uint) (*models.ItemRevision, error) {
+	var rev models.ItemRevision
+	err := db.Where("item_id = ? AND revision = ?", itemID, revision).First(&rev).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &rev, nil
+}
+
+// ListRevisions retrieves a page of an item's revision history, newest first
+// Revision history is always paginated by page.
+// Returns the revisions for the requested page, total number of revisions, ErrNotFound if
+// the actor cannot see the item, or an error if the operation fails
+func (s *ItemServiceDefault) ListRevisions(actor *Actor, id uint64, pagination *Pagination) ([]models.ItemRevision, int64, error) {
+	item, err := s.findHistoryItem(s.db, actor, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var revisions []models.ItemRevision
+	var total int64
+
+	query := s.db.Model(&models.ItemRevision{}).Where("item_id = ?", item.ID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (pagination.Page - 1) * pagination.Limit
+	err = s.db.Where("item_id = ?", item.ID).
+		Order("revision DESC").
+		Offset(offset).
+		Limit(pagination.Limit).
+		Find(&revisions).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return revisions, total, nil
+}
+
+// GetRevision retrieves a single revision of an item along with the changes it made
+// Returns the revision, ErrNotFound if the actor cannot see the item, ErrRevisionNotFound
+// if the revision does not exist, or an error if the operation fails
+func (s *ItemServiceDefault) GetRevision(actor *Actor, id uint64, revision uint) (*RevisionDiff, error) {
+	item, err := s.findHistoryItem(s.db, actor, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rev, err := findRevision(s.db, item.ID, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	// Revisions recorded before history was kept may be missing, so compare
+	// against the closest earlier revision rather than revision-1
+	var prev models.ItemRevision
+	err = s.db.Where("item_id = ? AND revision < ?", item.ID, revision).
+		Order("revision DESC").
+		First(&prev).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return &RevisionDiff{ItemRevision: *rev, Changes: diffRevisions(nil, rev)}, nil
+	case err != nil:
+		return nil, err
+	}
+
+	return &RevisionDiff{ItemRevision: *rev, Changes: diffRevisions(&prev, rev)}, nil
+}
+
+// RevertItem restores an item's name, description, visibility, metadata and tags to those of
+// an earlier revision. The revert is recorded as a new revision, so it can itself be reverted.
+// Revisions recorded before tags were kept leave the item's tags unchanged. The restored
+// metadata must satisfy the current metadata schema.
+// A non-zero version must match the item's current version for the revert to be applied.
+// Returns the updated item, ErrNotFound if the item doesn't exist, ErrRevisionNotFound if the
+// revision does not exist, ErrForbidden if the item is not owned by the actor,
+// ErrPreconditionFailed if the version does not match, a *MetadataError if the revision's
+// metadata is no longer valid, or an error if the update fails
+func (s *ItemServiceDefault) RevertItem(actor *Actor, id uint64, revision uint, version uint) (*models.Item, error) {
+	var reverted *models.Item
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		item, err := s.findModifiableItem(tx, actor, id)
+		if err != nil {
+			return err
+		}
+
+		if err := checkVersion(item, version); err != nil {
+			return err
+		}
+
+		rev, err := findRevision(tx, item.ID, revision)
+		if err != nil {
+			return err
+		}
+
+		metadata, err := s.normalizeMetadata(rev.Metadata)
+		if err != nil {
+			return err
+		}
+
+		if len(rev.Tags) > 0 {
+			var names []string
+			if err := json.Unmarshal(rev.Tags, &names); err != nil {
+				return fmt.Errorf("invalid revision tags: %w", err)
+			}
+
+			normalized, err := normalizeTags(names)
+			if err != nil {
+				return err
+			}
+
+			if err := replaceTags(tx, item, normalized); err != nil {
+				return err
+			}
+		}
+
+		reverted, err = writeItem(tx, actor, item, version, map[string]any{
+			"name":        rev.Name,
+			"description": rev.Description,
+			"visibility":  rev.Visibility,
+			"metadata":    metadata,
+		}, models.RevisionActionRevert)
+		return err
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return reverted, nil
+}
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.lumeweb.com/portal-plugin-template/internal/db/models"
+	"go.lumeweb.com/portal/core"
+	"gorm.io/gorm"
+)
+
+// Markers wrapped around matched terms in search snippets
+const (
+	HighlightStart = "<mark>"
+	HighlightEnd   = "</mark>"
+)
+
+// snippetWords is the number of words included in a search snippet
+const snippetWords = 16
+
+// SearchResult is a single ranked search hit
+type SearchResult struct {
+	Item    models.Item // The matching item
+	Score   float64     // Relevance score, higher is more relevant
+	Snippet string      // Excerpt of the item with matched terms highlighted
+}
+
+// SearchIndex provides ranked full-text search over public items.
+// Implementations rely on the database keeping its index in sync with the
+// items table, which is set up by the search migrations. Results are restricted
+// to items carrying every one of the given tags.
+type SearchIndex interface {
+	Search(query string, tags []string, pagination *Pagination) ([]SearchResult, int64, error)
+}
+
+// NewSearchIndex returns the search index implementation for the given database type.
+// Databases without a native full-text index fall back to LIKE matching.
+func NewSearchIndex(dbType string, db *gorm.DB) SearchIndex {
+	switch dbType {
+	case core.DB_TYPE_SQLITE:
+		return &sqliteSearchIndex{db: db}
+	case core.DB_TYPE_MYSQL:
+		return &mysqlSearchIndex{db: db}
+	default:
+		return &likeSearchIndex{db: db}
+	}
+}
+
+// searchRow is the scan target for ranked search queries
+type searchRow struct {
+	models.Item
+	Score   float64
+	Snippet string
+}
+
+// toResults converts scanned rows into search results, building a snippet
+// for rows where the database did not provide one
+func toResults(rows []searchRow, terms []string) []SearchResult {
+	results := make([]SearchResult, 0, len(rows))
+	for _, row := range rows {
+		snippet := row.Snippet
+		if snippet == "" {
+			snippet = itemSnippet(&row.Item, terms)
+		}
+		results = append(results, SearchResult{
+			Item:    row.Item,
+			Score:   row.Score,
+			Snippet: snippet,
+		})
+	}
+	return results
+}
+
+// sqliteSearchIndex searches the items_fts FTS5 table ranked by bm25
+type sqliteSearchIndex struct {
+	db *gorm.DB
+}
+
+func (i *sqliteSearchIndex) Search(query string, tags []string, pagination *Pagination) ([]SearchResult, int64, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return []SearchResult{}, 0, nil
+	}
+
+	// Quote every term and match it as a prefix: "foo"* "bar"*
+	quoted := make([]string, len(terms))
+	for n, term := range terms {
+		quoted[n] = fmt.Sprintf(`"%s"*`, term)
+	}
+	match := strings.Join(quoted, " ")
+
+	scope := func(db *gorm.DB) *gorm.DB {
+		return db.Joins("JOIN items_fts ON items_fts.rowid = items.id").
+			Where("items_fts MATCH ?", match).
+			Scopes(scopeListable(nil), scopeTagged(tags))
+	}
+
+	var total int64
+	if err := i.db.Model(&models.Item{}).Scopes(scope).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var rows []searchRow
+	snippet := fmt.Sprintf("snippet(items_fts, -1, '%s', '%s', '...', %d)", HighlightStart, HighlightEnd, snippetWords)
+	if err := i.db.Model(&models.Item{}).Scopes(scope).
+		Select("items.*, -bm25(items_fts) AS score, " + snippet + " AS snippet").
+		Order("score DESC").
+		Offset((pagination.Page - 1) * pagination.Limit).
+		Limit(pagination.Limit).
+		Scan(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return toResults(rows, terms), total, nil
+}
+
+// mysqlSearchIndex searches the FULLTEXT index on items(name, description) in boolean mode
+type mysqlSearchIndex struct {
+	db *gorm.DB
+}
+
+func (i *mysqlSearchIndex) Search(query string, tags []string, pagination *Pagination) ([]SearchResult, int64, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return []SearchResult{}, 0, nil
+	}
+
+	against := mysqlBooleanQuery(terms)
+
+	scope := func(db *gorm.DB) *gorm.DB {
+		return db.Where("MATCH(name, description) AGAINST (? IN BOOLEAN MODE)", against).
+			Scopes(scopeListable(nil), scopeTagged(tags))
+	}
+
+	var total int64
+	if err := i.db.Model(&models.Item{}).Scopes(scope).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var rows []searchRow
+	if err := i.db.Model(&models.Item{}).Scopes(scope).
+		Select("items.*, MATCH(name, description) AGAINST (? IN BOOLEAN MODE) AS score", against).
+		Order("score DESC").
+		Offset((pagination.Page - 1) * pagination.Limit).
+		Limit(pagination.Limit).
+		Scan(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return toResults(rows, terms), total, nil
+}
+
+// mysqlMinTokenSize is InnoDB's default innodb_ft_min_token_size, shorter words are not indexed
+const mysqlMinTokenSize = 3
+
+// mysqlStopwords are the words of InnoDB's default stopword list that are long enough to be indexed
+var mysqlStopwords = map[string]bool{
+	"about": true, "are": true, "com": true, "for": true, "from": true, "how": true,
+	"that": true, "the": true, "this": true, "und": true, "was": true, "what": true,
+	"when": true, "where": true, "who": true, "will": true, "with": true, "www": true,
+}
+
+// mysqlBooleanQuery builds a boolean mode query matching every term as a prefix: +foo* +bar*
+// Words InnoDB doesn't index, as they are too short or stopwords, never match, so they are
+// optional rather than required and only affect ranking where they do.
+func mysqlBooleanQuery(terms []string) string {
+	words := make([]string, len(terms))
+	for n, term := range terms {
+		words[n] = term + "*"
+		if utf8.RuneCountInString(term) >= mysqlMinTokenSize && !mysqlStopwords[term] {
+			words[n] = "+" + words[n]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// likeSearchIndex is the unranked LIKE based fallback for databases
+// without a supported full-text index
+type likeSearchIndex struct {
+	db *gorm.DB
+}
+
+func (i *likeSearchIndex) Search(query string, tags []string, pagination *Pagination) ([]SearchResult, int64, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return []SearchResult{}, 0, nil
+	}
+
+	scope := func(db *gorm.DB) *gorm.DB {
+		for _, term := range terms {
+			like := "%" + term + "%"
+			db = db.Where("name LIKE ? OR description LIKE ?", like, like)
+		}
+		return db.Scopes(scopeListable(nil), scopeTagged(tags))
+	}
+
+	var total int64
+	if err := i.db.Model(&models.Item{}).Scopes(scope).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var rows []searchRow
+	if err := i.db.Model(&models.Item{}).Scopes(scope).
+		Order("id").
+		Offset((pagination.Page - 1) * pagination.Limit).
+		Limit(pagination.Limit).
+		Scan(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return toResults(rows, terms), total, nil
+}
+
+// searchTerms splits a user query into lowercase words, dropping any
+// punctuation so it cannot be interpreted as search operators
+func searchTerms(query string) []string {
+	words := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for n, word := range words {
+		words[n] = strings.ToLower(word)
+	}
+	return words
+}
+
+// itemSnippet builds a highlighted snippet from the description, or the name
+// when the description does not contain any of the terms
+func itemSnippet(item *models.Item, terms []string) string {
+	if snippet, ok := highlight(item.Description, terms); ok {
+		return snippet
+	}
+	snippet, _ := highlight(item.Name, terms)
+	return snippet
+}
+
+// highlight returns a window of words around the first word starting with one
+// of the terms, with every such word wrapped in highlight markers.
+// The boolean result reports whether any term matched.
+func highlight(text string, terms []string) (string, bool) {
+	type span struct{ start, end int }
+
+	// Locate word boundaries as byte offsets into text
+	var words []span
+	start := -1
+	for pos, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && start < 0 {
+			start = pos
+		} else if !isWord && start >= 0 {
+			words = append(words, span{start, pos})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, span{start, len(text)})
+	}
+
+	matches := make([]bool, len(words))
+	first := -1
+	for n, w := range words {
+		word := strings.ToLower(text[w.start:w.end])
+		for _, term := range terms {
+			if strings.HasPrefix(word, term) {
+				matches[n] = true
+				break
+			}
+		}
+		if matches[n] && first < 0 {
+			first = n
+		}
+	}
+	if first < 0 {
+		return "", false
+	}
+
+	from := max(0, first-snippetWords/4)
+	to := min(len(words), from+snippetWords)
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("...")
+	}
+	pos := words[from].start
+	for n := from; n < to; n++ {
+		w := words[n]
+		b.WriteString(text[pos:w.start])
+		if matches[n] {
+			b.WriteString(HighlightStart + text[w.start:w.end] + HighlightEnd)
+		} else {
+			b.WriteString(text[w.start:w.end])
+		}
+		pos = w.end
+	}
+	if to < len(words) {
+		b.WriteString("...")
+	}
+
+	return b.String(), true
+}
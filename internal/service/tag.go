@@ -0,0 +1,365 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"go.lumeweb.com/portal-plugin-template/internal/db/models"
+	"gorm.io/gorm"
+)
+
+// TagCount is a tag together with the number of items carrying it
+type TagCount struct {
+	models.Tag
+	Count int64 // Number of matching items carrying the tag
+}
+
+// normalizeTag returns the stored form of a tag name or category
+func normalizeTag(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// validateTag normalizes a tag name or category and checks its length.
+// Names are required, categories may be empty.
+func validateTag(field string, value string, required bool) (string, error) {
+	value = normalizeTag(value)
+
+	if required && value == "" {
+		return "", fmt.Errorf("%w: tag %s is required", ErrValidation, field)
+	}
+	if utf8.RuneCountInString(value) > models.MaxTagLength {
+		return "", fmt.Errorf("%w: tag %s must be at most %d characters", ErrValidation, field, models.MaxTagLength)
+	}
+
+	return value, nil
+}
+
+// scopeTagged restricts a query to items carrying every one of the given tags
+func scopeTagged(tags []string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, tag := range tags {
+			db = db.Where(
+				"items.id IN (SELECT item_tags.item_id FROM item_tags JOIN tags ON tags.id = item_tags.tag_id WHERE tags.name = ?)",
+				normalizeTag(tag),
+			)
+		}
+		return db
+	}
+}
+
+// ListTags retrieves every tag ordered by category and name
+// A non-empty category only lists the tags in that category.
+func (s *ItemServiceDefault) ListTags(category string) ([]models.Tag, error) {
+	query := s.db.Order("category, name")
+	if category != "" {
+		query = query.Where("category = ?", normalizeTag(category))
+	}
+
+	var tags []models.Tag
+	if err := query.Find(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// CreateTag creates a new tag in the given category
+// Only admins may manage tags, users create tags implicitly by attaching them to items.
+// Returns the created tag, ErrForbidden if the actor is not an admin, ErrValidation if the
+// values are invalid, ErrConflict if a tag with the same name exists, or an error if the
+// operation fails
+func (s *ItemServiceDefault) CreateTag(actor *Actor, name string, category string) (*models.Tag, error) {
+	if actor == nil || !actor.Admin {
+		return nil, ErrForbidden
+	}
+
+	name, err := validateTag("name", name, true)
+	if err != nil {
+		return nil, err
+	}
+	category, err = validateTag("category", category, false)
+	if err != nil {
+		return nil, err
+	}
+
+	tag := &models.Tag{Name: name, Category: category}
+	if err := s.db.Create(tag).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	return tag, nil
+}
+
+// UpdateTag renames a tag or moves it to another category
+// As tags are part of the items carrying them, the change bumps the version of each of
+// those items and is recorded as a new revision of it.
+// Returns the updated tag, ErrForbidden if the actor is not an admin, ErrNotFound if the tag
+// doesn't exist, ErrValidation if the values are invalid, ErrConflict if another tag has the
+// new name, or an error if the update fails
+func (s *ItemServiceDefault) UpdateTag(actor *Actor, id uint64, name string, category string) (*models.Tag, error) {
+	if actor == nil || !actor.Admin {
+		return nil, ErrForbidden
+	}
+
+	name, err := validateTag("name", name, true)
+	if err != nil {
+		return nil, err
+	}
+	category, err = validateTag("category", category, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var tag models.Tag
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&tag, id).Error; err != nil {
+			return err
+		}
+
+		items, err := taggedItems(tx, tag.ID)
+		if err != nil {
+			return err
+		}
+
+		tag.Name = name
+		tag.Category = category
+		if err := tx.Model(&tag).Select("name", "category").Updates(&tag).Error; err != nil {
+			return err
+		}
+
+		return reviseItems(tx, actor, items)
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return &tag, nil
+}
+
+// DeleteTag deletes a tag and detaches it from every item
+// Each item carrying the tag has its version bumped and the change recorded as a new revision.
+// Returns ErrForbidden if the actor is not an admin, ErrNotFound if the tag doesn't exist,
+// or an error if the deletion fails
+func (s *ItemServiceDefault) DeleteTag(actor *Actor, id uint64) error {
+	if actor == nil || !actor.Admin {
+		return ErrForbidden
+	}
+
+	return translateError(s.db.Transaction(func(tx *gorm.DB) error {
+		items, err := taggedItems(tx, uint(id))
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Exec("DELETE FROM item_tags WHERE tag_id = ?", id).Error; err != nil {
+			return err
+		}
+
+		result := tx.Delete(&models.Tag{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		return reviseItems(tx, actor, items)
+	}))
+}
+
+// taggedItems loads the items carrying a tag, including those in the trash
+func taggedItems(tx *gorm.DB, tagID uint) ([]models.Item, error) {
+	var items []models.Item
+	err := tx.Unscoped().
+		Where("id IN (SELECT item_id FROM item_tags WHERE tag_id = ?)", tagID).
+		Order("id").
+		Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// reviseItems bumps the version of items whose tags were changed by a tag being renamed or
+// deleted, recording their new state as a revision. The items must have been loaded
+// within the same transaction.
+func reviseItems(tx *gorm.DB, actor *Actor, items []models.Item) error {
+	for i := range items {
+		if _, err := writeItem(tx, actor, &items[i], 0, map[string]any{}, models.RevisionActionUpdate); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetItemTags replaces the tags attached to an item, creating tags that don't exist yet
+// Tags created this way are uncategorised. Duplicate names are attached once. As tags are
+// part of the item, the change bumps its version and is recorded as a new revision.
+// A non-zero version must match the item's current version for the change to be applied.
+// Returns the item with its new tags, ErrNotFound if the item doesn't exist, ErrForbidden if
+// it is not owned by the actor, ErrValidation if a tag name is invalid, ErrPreconditionFailed
+// if the version does not match, or an error if the operation fails
+func (s *ItemServiceDefault) SetItemTags(actor *Actor, id uint64, version uint, names []string) (*models.Item, error) {
+	normalized, err := normalizeTags(names)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated *models.Item
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		item, err := s.findModifiableItem(tx, actor, id)
+		if err != nil {
+			return err
+		}
+
+		if err := checkVersion(item, version); err != nil {
+			return err
+		}
+
+		if err := replaceTags(tx, item, normalized); err != nil {
+			return err
+		}
+
+		updated, err = writeItem(tx, actor, item, version, map[string]any{}, models.RevisionActionUpdate)
+		return err
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return updated, nil
+}
+
+// normalizeTags validates tag names and returns their stored forms, each name once
+func normalizeTags(names []string) ([]string, error) {
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		name, err := validateTag("name", name, true)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(normalized, name) {
+			normalized = append(normalized, name)
+		}
+	}
+
+	return normalized, nil
+}
+
+// replaceTags replaces the tags attached to an item with the tags of the given normalized
+// names, creating tags that don't exist yet
+func replaceTags(tx *gorm.DB, item *models.Item, names []string) error {
+	tags, err := findOrCreateTags(tx, names)
+	if err != nil {
+		return err
+	}
+
+	return tx.Model(item).Association("Tags").Replace(tags)
+}
+
+// findOrCreateTags loads the tags with the given normalized names, creating missing ones
+func findOrCreateTags(tx *gorm.DB, names []string) ([]models.Tag, error) {
+	tags := make([]models.Tag, 0, len(names))
+	if len(names) == 0 {
+		return tags, nil
+	}
+
+	var existing []models.Tag
+	if err := tx.Where("name IN ?", names).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]models.Tag, len(existing))
+	for _, tag := range existing {
+		byName[tag.Name] = tag
+	}
+
+	for _, name := range names {
+		tag, ok := byName[name]
+		if !ok {
+			tag = models.Tag{Name: name}
+			if err := tx.Create(&tag).Error; err != nil {
+				return nil, err
+			}
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
+
+// CountTags counts the items the actor may list that match the filter, per tag
+// Tags without matching items are left out. A non-empty category only counts the tags in
+// that category. Counts are ordered from the most to the least used tag.
+// The filter's sort options are ignored.
+func (s *ItemServiceDefault) CountTags(actor *Actor, filter *ItemFilter, category string) ([]TagCount, error) {
+	query := s.db.Model(&models.Item{}).
+		Scopes(scopeListable(actor), filter.scope()).
+		Joins("JOIN item_tags ON item_tags.item_id = items.id").
+		Joins("JOIN tags ON tags.id = item_tags.tag_id")
+	if category != "" {
+		query = query.Where("tags.category = ?", normalizeTag(category))
+	}
+
+	counts := []TagCount{}
+	err := query.
+		Select("tags.id, tags.name, tags.category, tags.created_at, COUNT(items.id) AS count").
+		Group("tags.id, tags.name, tags.category, tags.created_at").
+		Order("count DESC, tags.name").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// preloadTags loads the tags of queried items, ordered by name
+func preloadTags(db *gorm.DB) *gorm.DB {
+	return db.Preload("Tags", func(db *gorm.DB) *gorm.DB {
+		return db.Order("tags.name")
+	})
+}
+
+// attachTags loads the tags of the given items in a single query
+func (s *ItemServiceDefault) attachTags(items []*models.Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+
+	var rows []struct {
+		models.Tag
+		ItemID uint
+	}
+	err := s.db.Table("tags").
+		Select("tags.*, item_tags.item_id").
+		Joins("JOIN item_tags ON item_tags.tag_id = tags.id").
+		Where("item_tags.item_id IN ?", ids).
+		Order("tags.name").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	byItem := make(map[uint][]models.Tag, len(items))
+	for _, row := range rows {
+		byItem[row.ItemID] = append(byItem[row.ItemID], row.Tag)
+	}
+
+	for _, item := range items {
+		item.Tags = byItem[item.ID]
+	}
+
+	return nil
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.lumeweb.com/portal-plugin-template/internal/db/models"
+	"gorm.io/datatypes"
+)
+
+// Item transfer formats used by exports and imports
+const (
+	FormatCSV    = "csv"    // Comma separated values with a header row
+	FormatNDJSON = "ndjson" // One JSON encoded item per line
+)
+
+// transferFormats lists the supported transfer formats
+var transferFormats = []string{FormatCSV, FormatNDJSON}
+
+// maxNDJSONLine is the longest NDJSON line accepted by imports
+const maxNDJSONLine = 1 << 20
+
+// csvColumns are the columns written by CSV exports, in order
+var csvColumns = []string{"id", "owner_id", "name", "description", "visibility", "metadata", "version", "created_at", "updated_at"}
+
+// ErrInvalidRecord is returned by an ItemReader when a single record cannot be parsed.
+// Reading can continue with the next record.
+var ErrInvalidRecord = fmt.Errorf("%w: invalid import record", ErrValidation)
+
+// ValidTransferFormat reports whether format is a supported transfer format
+func ValidTransferFormat(format string) bool {
+	return slices.Contains(transferFormats, format)
+}
+
+// TransferFormats returns the supported transfer formats
+func TransferFormats() []string {
+	return slices.Clone(transferFormats)
+}
+
+// ItemWriter encodes items in a transfer format
+type ItemWriter interface {
+	// Write encodes a single item
+	Write(item *models.Item) error
+	// Flush writes any buffered data to the underlying writer
+	Flush() error
+}
+
+// NewItemWriter returns an ItemWriter encoding items to w in the given format
+func NewItemWriter(format string, w io.Writer) (ItemWriter, error) {
+	switch format {
+	case FormatCSV:
+		return &csvItemWriter{w: csv.NewWriter(w)}, nil
+	case FormatNDJSON:
+		bw := bufio.NewWriter(w)
+		return &ndjsonItemWriter{w: bw, enc: json.NewEncoder(bw)}, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q", ErrValidation, format)
+	}
+}
+
+// csvItemWriter writes items as CSV rows, preceded by a header row
+type csvItemWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func (c *csvItemWriter) writeHeader() error {
+	if c.headerWritten {
+		return nil
+	}
+	c.headerWritten = true
+	return c.w.Write(csvColumns)
+}
+
+func (c *csvItemWriter) Write(item *models.Item) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	// Items without metadata are read back from the database as a JSON null
+	metadata := string(item.Metadata)
+	if metadata == "null" {
+		metadata = ""
+	}
+
+	return c.w.Write([]string{
+		strconv.FormatUint(uint64(item.ID), 10),
+		strconv.FormatUint(uint64(item.OwnerID), 10),
+		item.Name,
+		item.Description,
+		item.Visibility,
+		metadata,
+		strconv.FormatUint(uint64(item.Version), 10),
+		item.CreatedAt.UTC().Format(time.RFC3339),
+		item.UpdatedAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// Flush writes the header row even if no items were written, so empty exports
+// are still valid CSV documents
+func (c *csvItemWriter) Flush() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// ndjsonItemWriter writes each item as a JSON object on its own line
+type ndjsonItemWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (n *ndjsonItemWriter) Write(item *models.Item) error {
+	return n.enc.Encode(item)
+}
+
+func (n *ndjsonItemWriter) Flush() error {
+	return n.w.Flush()
+}
+
+// ItemRecord holds the importable fields of a single item
+type ItemRecord struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Visibility  string         `json:"visibility"`
+	Metadata    datatypes.JSON `json:"metadata"`
+}
+
+// ItemReader decodes item records from a transfer format
+type ItemReader interface {
+	// Read returns the next record, io.EOF when there are no more records, or an
+	// error wrapping ErrInvalidRecord if the record is malformed
+	Read() (*ItemRecord, error)
+}
+
+// NewItemReader returns an ItemReader decoding records from r in the given format
+// CSV input must start with a header row containing at least a name column. Other
+// columns are matched by name and unknown columns are ignored, so exports can be
+// imported unchanged.
+func NewItemReader(format string, r io.Reader) (ItemReader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVItemReader(r)
+	case FormatNDJSON:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLine)
+		return &ndjsonItemReader{scanner: scanner}, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q", ErrValidation, format)
+	}
+}
+
+// csvItemReader reads records from CSV rows using the columns named in the header
+type csvItemReader struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+func newCSVItemReader(r io.Reader) (*csvItemReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: missing CSV header row", ErrValidation)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid CSV header row: %v", ErrValidation, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	if _, ok := columns["name"]; !ok {
+		return nil, fmt.Errorf("%w: CSV header has no name column", ErrValidation)
+	}
+
+	return &csvItemReader{r: reader, columns: columns}, nil
+}
+
+func (c *csvItemReader) Read() (*ItemRecord, error) {
+	row, err := c.r.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRecord, err)
+		}
+		return nil, err
+	}
+
+	record := &ItemRecord{
+		Name:        c.field(row, "name"),
+		Description: c.field(row, "description"),
+		Visibility:  c.field(row, "visibility"),
+	}
+
+	// Metadata is embedded as a JSON document, an empty cell means no metadata
+	if metadata := c.field(row, "metadata"); metadata != "" {
+		if !json.Valid([]byte(metadata)) {
+			return nil, fmt.Errorf("%w: metadata is not valid JSON", ErrInvalidRecord)
+		}
+		record.Metadata = datatypes.JSON(metadata)
+	}
+
+	return record, nil
+}
+
+// field returns the value of the named column, or an empty string if the
+// column is absent or the row is too short
+func (c *csvItemReader) field(row []string, name string) string {
+	i, ok := c.columns[name]
+	if !ok || i >= len(row) {
+		return ""
+	}
+	return row[i]
+}
+
+// ndjsonItemReader reads one JSON record per line, skipping blank lines
+type ndjsonItemReader struct {
+	scanner *bufio.Scanner
+}
+
+func (n *ndjsonItemReader) Read() (*ItemRecord, error) {
+	for n.scanner.Scan() {
+		line := bytes.TrimSpace(n.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var record ItemRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRecord, err)
+		}
+		return &record, nil
+	}
+
+	if err := n.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// ExportItems streams every item the actor may list that matches the filter to fn,
+// in the filter's sort order. Items are read from the database one row at a time
+// so exports never hold the whole catalog in memory.
+// Returns ErrInvalidFilter if the filter is invalid, or the first error returned by fn
+func (s *ItemServiceDefault) ExportItems(actor *Actor, filter *ItemFilter, fn func(item *models.Item) error) error {
+	sort, err := filter.sortSpec()
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.db.Model(&models.Item{}).
+		Scopes(scopeListable(actor), filter.scope()).
+		Order(sort.orderClause(false)).
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.Item
+		if err := s.db.ScanRows(rows, &item); err != nil {
+			return err
+		}
+		if err := fn(&item); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"time"
+
+	"go.lumeweb.com/portal-plugin-template/internal"
+	pluginConfig "go.lumeweb.com/portal-plugin-template/internal/config"
+	"go.lumeweb.com/portal-plugin-template/internal/db/models"
+	"go.lumeweb.com/portal/core"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	purgeInterval  = time.Hour // How often expired items are purged from the trash
+	purgeBatchSize = 100       // Maximum number of items purged per statement
+)
+
+// scopeTrash restricts a query to soft-deleted items the actor may see.
+// Users see their own deleted items and admins see all of them.
+func scopeTrash(actor *Actor) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Unscoped().Where("deleted_at IS NOT NULL")
+		if actor.Admin {
+			return db
+		}
+		return db.Where("owner_id = ?", actor.UserID)
+	}
+}
+
+// ListTrash retrieves a sorted and paginated list of the actor's soft-deleted items
+// Admins see the deleted items of every user.
+// Returns the items for the requested page, total count of matching items, and any error
+func (s *ItemServiceDefault) ListTrash(actor *Actor, filter *ItemFilter, pagination *Pagination) ([]models.Item, int64, error) {
+	if actor == nil {
+		return nil, 0, ErrForbidden
+	}
+
+	return s.paginate(scopeTrash(actor), filter, pagination)
+}
+
+// RestoreItem moves a soft-deleted item out of the trash
+// A non-zero version must match the deleted item's version for it to be restored.
+// Restored items count against the item quotas again, including the quota of their owner
+// when an admin restores another user's item.
+// Returns the restored item, ErrNotFound if no such deleted item exists, ErrForbidden if it
+// is not owned by the actor, ErrPreconditionFailed if the version does not match, a
+// *QuotaError if an item quota is exhausted, or an error if the operation fails
+func (s *ItemServiceDefault) RestoreItem(actor *Actor, id uint64, version uint) (*models.Item, error) {
+	if actor == nil {
+		return nil, ErrForbidden
+	}
+
+	var restored *models.Item
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		item, err := s.findModifiableItem(tx.Scopes(scopeTrash(actor)), actor, id)
+		if err != nil {
+			return err
+		}
+
+		if err := checkVersion(item, version); err != nil {
+			return err
+		}
+
+		owner := actor
+		if item.OwnerID != actor.UserID {
+			owner = UserActor(s.ctx, item.OwnerID)
+		}
+
+		if err := s.checkQuota(tx, owner); err != nil {
+			return err
+		}
+
+		restored, err = writeItem(tx, actor, item, version, map[string]any{"deleted_at": nil}, models.RevisionActionRestore)
+		return err
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return restored, nil
+}
+
+// PurgeItem permanently removes an item, whether or not it has been soft-deleted
+// Only admins may purge items. A non-zero version must match the item's version.
+// Returns ErrForbidden if the actor is not an admin, ErrNotFound if the item doesn't exist,
+// ErrPreconditionFailed if the version does not match, or an error if the deletion fails
+func (s *ItemServiceDefault) PurgeItem(actor *Actor, id uint64, version uint) error {
+	if actor == nil || !actor.Admin {
+		return ErrForbidden
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		item, err := s.findItem(tx.Unscoped(), actor, id)
+		if err != nil {
+			return err
+		}
+
+		if err := checkVersion(item, version); err != nil {
+			return err
+		}
+
+		return s.purgeItems(tx, []uint{item.ID})
+	})
+
+	return translateError(err)
+}
+
+// PurgeExpiredItems permanently removes items that were soft-deleted before the cutoff
+// Returns the number of items purged and any error
+func (s *ItemServiceDefault) PurgeExpiredItems(cutoff time.Time) (int64, error) {
+	var purged int64
+
+	for {
+		var ids []uint
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			// Expired items are selected and locked within the transaction deleting them,
+			// so an item restored meanwhile is no longer selected and stays
+			err := tx.Unscoped().Model(&models.Item{}).
+				Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).
+				Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+				Order("id").
+				Limit(purgeBatchSize).
+				Pluck("id", &ids).Error
+			if err != nil || len(ids) == 0 {
+				return err
+			}
+
+			return s.purgeItems(tx, ids)
+		})
+		if err != nil {
+			return purged, err
+		}
+
+		if len(ids) == 0 {
+			return purged, nil
+		}
+
+		purged += int64(len(ids))
+	}
+}
+
+// purgeItems permanently deletes the given items, their history, tags and attachments using
+// the given connection. Stored files are content addressed and may be shared, so only the
+// links to them are removed.
+func (s *ItemServiceDefault) purgeItems(tx *gorm.DB, ids []uint) error {
+	if err := tx.Where("item_id IN ?", ids).Delete(&models.ItemRevision{}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("item_id IN ?", ids).Delete(&models.ItemAttachment{}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Exec("DELETE FROM item_tags WHERE item_id IN ?", ids).Error; err != nil {
+		return err
+	}
+
+	return tx.Unscoped().Where("id IN ?", ids).Delete(&models.Item{}).Error
+}
+
+// trashRetention returns how long deleted items are kept, or zero if they are kept forever
+func (s *ItemServiceDefault) trashRetention() time.Duration {
+	cfg, ok := s.ctx.Config().GetProtocol(internal.PLUGIN_NAME).(*pluginConfig.Config)
+	if !ok || cfg.TrashRetentionDays <= 0 {
+		return 0
+	}
+
+	return time.Duration(cfg.TrashRetentionDays) * 24 * time.Hour
+}
+
+// claimPurge claims the purge due at now for this node, returning whether it was claimed
+// The recorded run time is only moved forward when no other node claimed a purge within
+// the last half interval, so one node purges per interval however many run the schedule.
+func (s *ItemServiceDefault) claimPurge(now time.Time) (bool, error) {
+	run := models.PurgeRun{ID: 1}
+	if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&run).Error; err != nil {
+		return false, err
+	}
+
+	result := s.db.Model(&models.PurgeRun{}).
+		Where("id = ? AND (ran_at IS NULL OR ran_at < ?)", run.ID, now.Add(-purgeInterval/2)).
+		Update("ran_at", now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// runPurge periodically purges items that have been in the trash longer than the
+// configured retention, until the context is cancelled
+// Each portal node runs the schedule, but only the node claiming a run purges.
+func (s *ItemServiceDefault) runPurge(ctx core.Context) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if retention := s.trashRetention(); retention > 0 {
+			s.purgeExpired(retention)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeExpired purges the items kept in the trash longer than retention, if this node
+// claims the purge
+func (s *ItemServiceDefault) purgeExpired(retention time.Duration) {
+	now := time.Now()
+
+	claimed, err := s.claimPurge(now)
+	if err != nil {
+		s.logger.Error("failed to claim purge of expired items", zap.Error(err))
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	purged, err := s.PurgeExpiredItems(now.Add(-retention))
+	if err != nil {
+		s.logger.Error("failed to purge expired items", zap.Error(err))
+	} else if purged > 0 {
+		s.logger.Info("purged expired items", zap.Int64("count", purged))
+	}
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.lumeweb.com/portal-plugin-template/internal"
+	pluginConfig "go.lumeweb.com/portal-plugin-template/internal/config"
+)
+
+// textLimits returns the configured maximum name and description lengths in characters
+// A limit of zero means unlimited.
+func (s *ItemServiceDefault) textLimits() (name int, description int) {
+	if apiCfg, ok := s.ctx.Config().GetAPI(internal.PLUGIN_NAME).(*pluginConfig.APIConfig); ok {
+		return apiCfg.MaxNameLength, apiCfg.MaxDescriptionLength
+	}
+	return 0, 0
+}
+
+// validateName checks that an item name is a non-empty single line of printable
+// characters within the configured length limit
+func (s *ItemServiceDefault) validateName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("%w: name is required", ErrValidation)
+	}
+
+	limit, _ := s.textLimits()
+	return validateText("name", name, limit, false)
+}
+
+// validateDescription checks that an item description consists of printable characters,
+// newlines and tabs within the configured length limit
+func (s *ItemServiceDefault) validateDescription(description string) error {
+	_, limit := s.textLimits()
+	return validateText("description", description, limit, true)
+}
+
+// validateText checks the length and characters of a text field
+func validateText(field string, value string, limit int, multiline bool) error {
+	if limit > 0 && utf8.RuneCountInString(value) > limit {
+		return fmt.Errorf("%w: %s must be at most %d characters", ErrValidation, field, limit)
+	}
+
+	for _, r := range value {
+		if r != utf8.RuneError && (unicode.IsPrint(r) || (multiline && (r == '\n' || r == '\r' || r == '\t'))) {
+			continue
+		}
+		return fmt.Errorf("%w: %s contains characters that are not allowed", ErrValidation, field)
+	}
+
+	return nil
+}
@@ -0,0 +1,11 @@
+Dear {{.UserName}},
+
+A batch of item operations has been processed in {{.PortalName}}:
+
+Created: {{.Created}}
+Updated: {{.Updated}}
+Deleted: {{.Deleted}}
+Failed: {{.Failed}}
+
+Best regards,
+The {{.PortalName}} Team
@@ -0,0 +1 @@
+Batch Completed: {{.Succeeded}} Item Operations Applied
@@ -11,6 +11,7 @@ var mailerTemplates embed.FS
 
 const (
 	MAILER_TPL_ITEM_CREATED = "item_created"
+	MAILER_TPL_ITEMS_BATCH  = "items_batch"
 )
 
 func GetMailerTemplates() (map[string]core.MailerTemplate, error) {
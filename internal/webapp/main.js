@@ -6,35 +6,57 @@
  * - Loading and displaying items with pagination
  * - Creating new items
  * - Searching existing items
+ * - Sorting and filtering the item list
+ * - Browsing items by tag with per-tag counts
+ * - Uploading files with a live progress bar
  * - Basic error handling
  * - UI state management
  */
 
 /**
  * Global State Management
- * Tracks the current page and items per page for pagination
+ * Tracks the current cursor or search page and items per page for pagination
  */
 let currentPage = 1;
+let currentCursor = '';
+let currentQuery = '';
+let currentFilters = {};
+let currentTags = [];
 const itemsPerPage = 10;
 
+/**
+ * Labels shown for each phase an upload moves through
+ */
+const uploadPhaseLabels = {
+    receiving: 'Uploading',
+    hashing: 'Hashing',
+    storing: 'Storing',
+    scanning: 'Scanning',
+    done: 'Done',
+    failed: 'Failed',
+};
+
 /**
  * API Interaction Functions
  * These functions handle all communication with the backend API
  */
 /**
- * Loads a page of items from the API
- * @param {number} page - The page number to load (defaults to 1)
+ * Loads a page of items from the API using cursor pagination
+ * @param {string} cursor - The cursor of the page to load (defaults to the first page)
  * @returns {Promise<void>}
  */
-async function loadItems(page = 1) {
+async function loadItems(cursor = '') {
     try {
-        const response = await fetch(`/api/items?page=${page}&limit=${itemsPerPage}`);
+        const params = listParams({ cursor, limit: itemsPerPage });
+        const response = await fetch(`/api/items?${params}`);
         if (!response.ok) throw new Error('Failed to load items');
         
         const data = await response.json();
         renderItems(data);
-        renderPagination(data);
-        currentPage = page;
+        renderCursorPagination(data);
+        currentCursor = cursor;
+        currentQuery = '';
+        await loadTagCounts();
     } catch (error) {
         console.error('Error loading items:', error);
         // TODO: Show user-friendly error message
@@ -59,32 +81,182 @@ async function createItem(name, description) {
         
         if (!response.ok) throw new Error('Failed to create item');
         
-        await loadItems(currentPage); // Refresh the current page
+        await loadItems(currentCursor); // Refresh the current page
     } catch (error) {
         console.error('Error creating item:', error);
         // TODO: Show user-friendly error message
     }
 }
 
+/**
+ * Uploads a file and follows its progress until the upload workflow has finished
+ * The request body's progress is reported while it is sent, the server side phases
+ * are then streamed from the upload's event stream
+ * @param {File} file - The file to upload
+ * @returns {Promise<void>}
+ */
+async function uploadFile(file) {
+    renderUploadProgress({ phase: 'receiving', size: file.size, uploaded: 0, processed: 0 });
+
+    try {
+        const upload = await sendUpload(file, (loaded) => {
+            renderUploadProgress({ phase: 'receiving', size: file.size, uploaded: loaded, processed: loaded });
+        });
+        watchUpload(upload.id);
+    } catch (error) {
+        console.error('Error uploading file:', error);
+        renderUploadProgress({ phase: 'failed', size: file.size, processed: 0, error: error.message });
+    }
+}
+
+/**
+ * Sends a file to the upload endpoint as a multipart body
+ * XMLHttpRequest is used as fetch can't report the progress of a request body
+ * @param {File} file - The file to upload
+ * @param {Function} onProgress - Called with the number of bytes sent so far
+ * @returns {Promise<Object>} The upload response carrying the upload ID
+ */
+function sendUpload(file, onProgress) {
+    return new Promise((resolve, reject) => {
+        const body = new FormData();
+        // Declaring the size before the file lets the server stream the file as it arrives
+        body.append('size', file.size);
+        body.append('file', file);
+
+        const request = new XMLHttpRequest();
+        request.open('POST', '/api/uploads');
+        request.responseType = 'json';
+        request.upload.addEventListener('progress', (e) => onProgress(e.loaded));
+        request.addEventListener('load', () => {
+            if (request.status === 202) {
+                resolve(request.response);
+            } else {
+                reject(new Error(request.response?.error?.message || 'Upload failed'));
+            }
+        });
+        request.addEventListener('error', () => reject(new Error('Upload failed')));
+        request.send(body);
+    });
+}
+
+/**
+ * Follows the progress of an upload through its event stream until it is done or failed
+ * @param {string} id - The upload ID
+ */
+function watchUpload(id) {
+    const events = new EventSource(`/api/uploads/${encodeURIComponent(id)}/events`);
+
+    const update = (e) => {
+        const state = JSON.parse(e.data);
+        renderUploadProgress(state);
+
+        // The server closes the stream after the last phase, don't let EventSource reconnect
+        if (state.phase === 'done' || state.phase === 'failed') {
+            events.close();
+        }
+    };
+    events.addEventListener('phase', update);
+    events.addEventListener('progress', update);
+    events.addEventListener('error', () => {
+        events.close();
+        renderUploadProgress({ phase: 'failed', processed: 0, error: 'Lost connection to the upload' });
+    });
+}
+
 /**
  * Searches for items matching the given query
  * @param {string} query - The search query
+ * @param {number} page - The page of results to load (defaults to 1)
  * @returns {Promise<void>}
  */
-async function searchItems(query) {
+async function searchItems(query, page = 1) {
     try {
-        const response = await fetch(`/api/items/search?q=${encodeURIComponent(query)}`);
+        const params = new URLSearchParams({ q: query, page, limit: itemsPerPage });
+        currentTags.forEach(tag => params.append('tag', tag));
+        const response = await fetch(`/api/items/search?${params}`);
         if (!response.ok) throw new Error('Search failed');
         
         const data = await response.json();
         renderItems(data);
-        document.getElementById('pagination').style.display = 'none';
+        renderPagination(data);
+        currentQuery = query;
+        currentPage = page;
     } catch (error) {
         console.error('Error searching items:', error);
         // TODO: Show user-friendly error message
     }
 }
 
+/**
+ * Loads the number of items per tag within the current filters and renders them as facets
+ * @returns {Promise<void>}
+ */
+async function loadTagCounts() {
+    try {
+        const response = await fetch(`/api/tags/counts?${listParams({})}`);
+        if (!response.ok) throw new Error('Failed to load tag counts');
+
+        const data = await response.json();
+        renderTagFacets(data);
+    } catch (error) {
+        console.error('Error loading tag counts:', error);
+        // TODO: Show user-friendly error message
+    }
+}
+
+/**
+ * Builds the query parameters for list requests from the current filters and selected tags
+ * @param {Object} extra - Additional parameters to include
+ * @returns {URLSearchParams} The query parameters
+ */
+function listParams(extra) {
+    const params = new URLSearchParams({ ...extra, ...currentFilters });
+    currentTags.forEach(tag => params.append('tag', tag));
+    return params;
+}
+
+/**
+ * Adds a tag to the selected tags, or removes it if it is already selected,
+ * and reloads the list from the first page
+ * @param {string} tag - The tag to toggle
+ */
+function toggleTag(tag) {
+    if (currentTags.includes(tag)) {
+        currentTags = currentTags.filter(t => t !== tag);
+    } else {
+        currentTags = [...currentTags, tag];
+    }
+
+    if (currentQuery) {
+        searchItems(currentQuery, 1);
+    } else {
+        loadItems();
+    }
+}
+
+/**
+ * Reads the sort and filter form into query parameters for the item list
+ * Empty fields are left out and dates are sent as RFC 3339 timestamps
+ * @returns {Object} The query parameters to send with item list requests
+ */
+function readFilters() {
+    const filters = {
+        sort: document.getElementById('sortInput').value,
+        order: document.getElementById('orderInput').value,
+    };
+
+    const namePrefix = document.getElementById('namePrefixInput').value;
+    if (namePrefix) filters.name_prefix = namePrefix;
+
+    const createdAfter = document.getElementById('createdAfterInput').value;
+    if (createdAfter) filters.created_after = new Date(createdAfter).toISOString();
+
+    const updatedBefore = document.getElementById('updatedBeforeInput').value;
+    if (updatedBefore) filters.updated_before = new Date(updatedBefore).toISOString();
+
+    return filters;
+}
+
 /**
  * UI Rendering Functions
  * These functions handle updating the DOM with new data
@@ -104,14 +276,46 @@ function renderItems(data) {
     itemsDiv.innerHTML = data.items.map(item => `
         <div class="item">
             <h3>${escapeHtml(item.name)}</h3>
-            <p>${escapeHtml(item.description || '')}</p>
+            <p>${item.snippet ? renderSnippet(item.snippet) : escapeHtml(item.description || '')}</p>
+            ${renderTags(item.tags || [])}
             <button onclick="deleteItem(${item.id})">Delete</button>
         </div>
     `).join('');
 }
 
 /**
- * Renders the pagination controls
+ * Renders an item's tags as buttons that filter the list by that tag
+ * @param {Array} tags - The tags attached to the item
+ * @returns {string} The tag list HTML
+ */
+function renderTags(tags) {
+    if (!tags.length) return '';
+
+    return `<div class="tags">${tags.map(tag => `
+        <button class="tag" data-tag="${escapeHtml(tag.name)}">${escapeHtml(tag.name)}</button>
+    `).join('')}</div>`;
+}
+
+/**
+ * Renders the tag facets with their item counts, highlighting the selected tags
+ * @param {Object} data - The data containing the per-tag counts
+ */
+function renderTagFacets(data) {
+    const tagsDiv = document.getElementById('tagFacets');
+
+    // Keep selected tags visible even when they no longer match any item
+    const counts = new Map(data.tags.map(tag => [tag.name, tag.count]));
+    currentTags.forEach(tag => { if (!counts.has(tag)) counts.set(tag, 0); });
+
+    tagsDiv.innerHTML = [...counts].map(([name, count]) => `
+        <button class="tag ${currentTags.includes(name) ? 'selected' : ''}" data-tag="${escapeHtml(name)}">
+            ${escapeHtml(name)} (${count})
+        </button>
+    `).join('');
+}
+
+/**
+ * Renders the page based pagination controls used for search results
  * @param {Object} data - The data containing pagination information
  */
 function renderPagination(data) {
@@ -126,13 +330,82 @@ function renderPagination(data) {
     paginationDiv.style.display = 'block';
     paginationDiv.innerHTML = `
         <button ${data.page <= 1 ? 'disabled' : ''} 
-                onclick="loadItems(${data.page - 1})">Previous</button>
+                onclick="goToPage(${data.page - 1})">Previous</button>
         <span>Page ${data.page} of ${totalPages}</span>
         <button ${data.page >= totalPages ? 'disabled' : ''} 
-                onclick="loadItems(${data.page + 1})">Next</button>
+                onclick="goToPage(${data.page + 1})">Next</button>
     `;
 }
 
+/**
+ * Loads the given page of search results
+ * @param {number} page - The page number to load
+ */
+function goToPage(page) {
+    searchItems(currentQuery, page);
+}
+
+/**
+ * Renders the upload progress bar for the current phase of an upload
+ * Phases that don't report byte progress show an indeterminate bar
+ * @param {Object} state - The upload state, as returned by the upload endpoints
+ */
+function renderUploadProgress(state) {
+    document.getElementById('uploadProgressGroup').hidden = false;
+    const progress = document.getElementById('uploadProgress');
+    const status = document.getElementById('uploadStatus');
+
+    const label = uploadPhaseLabels[state.phase] || state.phase;
+    status.classList.toggle('failed', state.phase === 'failed');
+
+    if (state.phase === 'done') {
+        progress.value = 100;
+        status.textContent = `${label}: ${state.hash}`;
+    } else if (state.phase === 'failed') {
+        progress.value = 0;
+        status.textContent = state.error ? `${label}: ${state.error}` : label;
+    } else if (state.size) {
+        const percent = Math.min(100, Math.floor(state.processed / state.size * 100));
+        progress.value = percent;
+        status.textContent = `${label} ${percent}%`;
+    } else {
+        progress.removeAttribute('value');
+        status.textContent = `${label}...`;
+    }
+}
+
+/**
+ * Renders the previous/next controls for cursor paginated listings
+ * @param {Object} data - The data containing the next and previous cursors
+ */
+function renderCursorPagination(data) {
+    const paginationDiv = document.getElementById('pagination');
+
+    if (!data.prev_cursor && !data.next_cursor) {
+        paginationDiv.style.display = 'none';
+        return;
+    }
+
+    paginationDiv.style.display = 'block';
+    paginationDiv.innerHTML = `
+        <button ${data.prev_cursor ? '' : 'disabled'} 
+                onclick="loadItems('${data.prev_cursor || ''}')">Previous</button>
+        <button ${data.next_cursor ? '' : 'disabled'} 
+                onclick="loadItems('${data.next_cursor || ''}')">Next</button>
+    `;
+}
+
+/**
+ * Renders a search snippet, keeping only the <mark> highlight tags as HTML
+ * @param {string} snippet - The snippet returned by the search API
+ * @returns {string} The escaped snippet with highlights restored
+ */
+function renderSnippet(snippet) {
+    return escapeHtml(snippet)
+        .replace(/&lt;mark&gt;/g, '<mark>')
+        .replace(/&lt;\/mark&gt;/g, '</mark>');
+}
+
 /**
  * Utility function to escape HTML special characters
  * @param {string} unsafe - The string to escape
@@ -156,7 +429,7 @@ function escapeHtml(unsafe) {
  */
 document.addEventListener('DOMContentLoaded', () => {
     // Load initial items on page load
-    loadItems(1);
+    loadItems();
 
     // Setup search form handler
     const searchForm = document.getElementById('searchForm');
@@ -164,12 +437,35 @@ document.addEventListener('DOMContentLoaded', () => {
         e.preventDefault();
         const query = document.getElementById('searchInput').value;
         if (query) {
-            searchItems(query);
+            searchItems(query, 1);
         } else {
-            loadItems(1); // Reset to first page if search is cleared
+            loadItems(); // Reset to first page if search is cleared
         }
     });
 
+    // Setup sort and filter form handler
+    const filterForm = document.getElementById('filterForm');
+    filterForm.addEventListener('submit', (e) => {
+        e.preventDefault();
+        currentFilters = readFilters();
+        loadItems(); // Cursors are tied to the sort order, so start over
+    });
+
+    // Toggle tag filters when a facet or an item's tag is clicked
+    document.addEventListener('click', (e) => {
+        const button = e.target.closest('button.tag');
+        if (button) toggleTag(button.dataset.tag);
+    });
+
+    // Setup file upload form handler
+    const uploadForm = document.getElementById('uploadForm');
+    uploadForm.addEventListener('submit', (e) => {
+        e.preventDefault();
+        const file = document.getElementById('fileInput').files[0];
+        if (file) uploadFile(file);
+        uploadForm.reset(); // Clear the file input, the progress stays visible
+    });
+
     // Setup item creation form handler
     const createForm = document.getElementById('createForm');
     createForm.addEventListener('submit', (e) => {
@@ -43,6 +43,14 @@ func init() {
 		},
 		Models: []any{
 			&models.Item{},
+			&models.ItemRevision{},
+			&models.Tag{},
+			&models.ItemAttachment{},
+			&models.UploadProgress{},
+			&models.UploadData{},
+			&models.QuotaLock{},
+			&models.ImportProgress{},
+			&models.PurgeRun{},
 		},
 		Migrations: core.DBMigration{
 			core.DB_TYPE_MYSQL:  migrations.GetMySQL(),